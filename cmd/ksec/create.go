@@ -5,20 +5,44 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
 )
 
 var createCmd = &cobra.Command{
 	Use:   "create [secret...]",
 	Short: "Create a Secret",
-	Args:  cobra.MinimumNArgs(1),
-	RunE:  createCommand,
+	Long: `Create empty Secrets.
+
+Secret types that require keys, such as tls, cannot be created empty. The
+error names the command that creates them with their keys.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: createCommand,
+}
+
+// createHints are the commands that create Secrets of the types requiring
+// keys, %s is the name of the Secret
+var createHints = map[v1.SecretType]string{
+	v1.SecretTypeTLS:              "ksec tls set %s --cert CERT --key KEY",
+	v1.SecretTypeDockerConfigJson: "ksec registry add %s --registry SERVER --username USER --password-stdin",
+	v1.SecretTypeDockercfg:        "ksec set %s --type dockercfg .dockercfg=@FILE",
+	v1.SecretTypeBasicAuth:        "ksec set %s --type basic-auth username=USER password=-",
+	v1.SecretTypeSSHAuth:          "ksec set %s --type ssh-auth ssh-privatekey=@KEY",
+	v1.SecretTypeBootstrapToken:   "ksec set %s --type bootstrap-token token-id=ID token-secret=-",
 }
 
 func createCommand(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	secretType, err := getSecretType(cmd, nil)
+	if err != nil {
+		return err
+	}
+	if hint, ok := createHints[secretType]; ok {
+		return fmt.Errorf("%s secrets cannot be created without keys, use: %s", secretType, fmt.Sprintf(hint, args[0]))
+	}
+
 	for _, name := range args {
-		if _, err := secretsClient.Create(ctx, name); err != nil {
+		if _, err := secretsClient.CreateWithType(ctx, name, secretType, nil); err != nil {
 			return err
 		}
 		fmt.Printf("Created secret \"%s\"\n", name)
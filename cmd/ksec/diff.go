@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kanopy-platform/ksec/pkg/dotenv"
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [source] [target]",
+	Short: "Compare the keys of two Secrets or .env files",
+	Long: `Compare the keys of two Secrets or .env files.
+
+Each operand is a local file when a file with that path exists, otherwise it
+is a Secret reference in the form [context:][namespace/]name.
+
+Only the keys that differ are printed, their values only with --show-values.
+The exit code is 0 when both sides are identical, 1 when they differ and 2 on errors.`,
+	Example: `  ksec diff api-creds .env
+  ksec diff staging/api-creds production/api-creds
+  ksec diff staging-cluster:app/api-creds prod-cluster:app/api-creds`,
+	Args: cobra.ExactArgs(2),
+	RunE: diffCommand,
+}
+
+const (
+	exitCodeDrift     = 1
+	exitCodeDiffError = 2
+)
+
+func diffCommand(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	showValues, err := cmd.Flags().GetBool("show-values")
+	if err != nil {
+		return err
+	}
+
+	source, err := loadDiffOperand(ctx, args[0])
+	if err != nil {
+		return &exitError{code: exitCodeDiffError, err: err}
+	}
+	target, err := loadDiffOperand(ctx, args[1])
+	if err != nil {
+		return &exitError{code: exitCodeDiffError, err: err}
+	}
+
+	changes := models.Diff(source, target)
+	if len(changes) == 0 {
+		fmt.Printf("%s and %s are identical\n", args[0], args[1])
+		return nil
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n", args[0], args[1])
+	for _, change := range changes {
+		if !showValues {
+			fmt.Printf("%s %s\n", diffMarkers[change.Type], change.Key)
+			continue
+		}
+
+		switch change.Type {
+		case models.ChangeAdded:
+			fmt.Printf("+ %s: %q\n", change.Key, change.New)
+		case models.ChangeRemoved:
+			fmt.Printf("- %s: %q\n", change.Key, change.Old)
+		case models.ChangeChanged:
+			fmt.Printf("~ %s: %q => %q\n", change.Key, change.Old, change.New)
+		}
+	}
+
+	// drift is reported through the exit code only
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return &exitError{code: exitCodeDrift, err: fmt.Errorf("%d key(s) differ", len(changes))}
+}
+
+// diffMarkers prefix the keys that differ. Without --show-values nothing
+// derived from the values is printed, not even a digest, since the digest of
+// a short value can be reversed by guessing.
+var diffMarkers = map[models.ChangeType]string{
+	models.ChangeAdded:   "+",
+	models.ChangeRemoved: "-",
+	models.ChangeChanged: "~",
+}
+
+// loadDiffOperand reads the data of a local file or Secret reference
+func loadDiffOperand(ctx context.Context, arg string) (map[string][]byte, error) {
+	if fileExists(arg) {
+		file, err := os.Open(arg)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		data, err := dotenv.Parse(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", arg, err)
+		}
+		return data, nil
+	}
+
+	kubeContext, namespace, name := parseSecretRef(arg)
+	if name == "" {
+		return nil, fmt.Errorf("invalid secret reference %q, expected [context:][namespace/]name", arg)
+	}
+
+	client := secretsClient
+	if kubeContext != "" {
+		opts := *clientOptions
+		opts.Context = kubeContext
+		opts.Namespace = namespace
+
+		var err error
+		client, err = models.NewSecretsClient(&opts)
+		if err != nil {
+			return nil, err
+		}
+	} else if namespace != "" {
+		client = secretsClient.ForNamespace(namespace)
+	}
+
+	secret, err := client.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
+
+// parseSecretRef splits [context:][namespace/]name. Context names may contain
+// colons themselves, so the last one separates the context.
+func parseSecretRef(ref string) (kubeContext, namespace, name string) {
+	if idx := strings.LastIndex(ref, ":"); idx >= 0 {
+		kubeContext, ref = ref[:idx], ref[idx+1:]
+	}
+	if idx := strings.Index(ref, "/"); idx >= 0 {
+		namespace, ref = ref[:idx], ref[idx+1:]
+	}
+	return kubeContext, namespace, ref
+}
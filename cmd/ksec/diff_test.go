@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSecretRef(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		ref       string
+		context   string
+		namespace string
+		name      string
+	}{
+		{ref: "api-creds", name: "api-creds"},
+		{ref: "prod/api-creds", namespace: "prod", name: "api-creds"},
+		{ref: "staging:app/api-creds", context: "staging", namespace: "app", name: "api-creds"},
+		{ref: "arn:aws:eks:us-east-1:123:cluster/prod:app/api-creds", context: "arn:aws:eks:us-east-1:123:cluster/prod", namespace: "app", name: "api-creds"},
+		{ref: "staging:api-creds", context: "staging", name: "api-creds"},
+	}
+
+	for _, test := range tests {
+		kubeContext, namespace, name := parseSecretRef(test.ref)
+		assert.Equal(t, test.context, kubeContext, test.ref)
+		assert.Equal(t, test.namespace, namespace, test.ref)
+		assert.Equal(t, test.name, name, test.ref)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/kanopy-platform/ksec/pkg/dotenv"
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	editFormatDotenv = "dotenv"
+	editFormatYAML   = "yaml"
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit [secret]",
+	Short: "Edit the values of a Secret in an editor",
+	Long: `Edit the decoded values of a Secret in $KSEC_EDITOR or $EDITOR.
+
+The values are written to a temporary file only readable by the current user,
+which is removed afterwards. When the file cannot be parsed the editor is
+opened again with the error at the top, closing it without changes gives up.
+Only the keys that were changed are written to the Secret.`,
+	Args: cobra.ExactArgs(1),
+	RunE: editCommand,
+}
+
+// runEditor opens path in the editor of the user and waits for it to exit
+var runEditor = func(cmd *cobra.Command, path string) error {
+	editor := os.Getenv("KSEC_EDITOR")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// the editor may have arguments, e.g. "code --wait"
+	fields := strings.Fields(editor)
+	child := exec.Command(fields[0], append(fields[1:], path)...)
+	child.Stdin = cmd.InOrStdin()
+	child.Stdout = cmd.OutOrStdout()
+	child.Stderr = cmd.ErrOrStderr()
+	if err := child.Run(); err != nil {
+		return fmt.Errorf("editor %s: %w", editor, err)
+	}
+	return nil
+}
+
+func editCommand(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	ctx := context.Background()
+
+	opts, err := getPlanOptions(cmd)
+	if err != nil {
+		return err
+	}
+	format, err := cmd.Flags().GetString("format")
+	if err != nil {
+		return err
+	}
+	if format != editFormatDotenv && format != editFormatYAML {
+		return fmt.Errorf(`invalid --format value %q, must be "%s" or "%s"`, format, editFormatDotenv, editFormatYAML)
+	}
+
+	secret, err := secretsClient.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	body, err := encodeEditDocument(format, secret.Data)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp("", fmt.Sprintf("ksec-edit-*.%s", strings.TrimPrefix(format, "dot")))
+	if err != nil {
+		return err
+	}
+	path := file.Name()
+	file.Close()
+	keep := false
+	defer func() {
+		if !keep {
+			os.Remove(path)
+		}
+	}()
+
+	var data map[string][]byte
+	var p *plan
+	header := editHeader(name, nil)
+	original := append([]byte(header), body...)
+	content := original
+	var editErr error
+
+	for {
+		if err := writeSecretFile(path, content); err != nil {
+			return err
+		}
+		if err := runEditor(cmd, path); err != nil {
+			return err
+		}
+		edited, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		// closing the editor without fixing the error gives up, like kubectl edit
+		if editErr != nil && bytes.Equal(edited, content) {
+			return fmt.Errorf("edit canceled, no valid changes were saved: %w", editErr)
+		}
+		if bytes.Equal(edited, original) || isBlankDocument(edited) {
+			fmt.Println("Edit canceled, no changes made")
+			return nil
+		}
+
+		data, err = decodeEditDocument(format, edited)
+		if err == nil {
+			p = newPlan(name, secret, data, models.StaleKeys(secret, data))
+			err = p.validate(secret.Type)
+		}
+		if err == nil {
+			break
+		}
+
+		// open the editor again with the error at the top, like kubectl edit
+		editErr = err
+		edited = bytes.TrimPrefix(edited, []byte(header))
+		header = editHeader(name, err)
+		content = append([]byte(header), edited...)
+	}
+
+	client, ok, err := opts.confirm(p, false)
+	if !ok {
+		return err
+	}
+
+	latest, err := secretsClient.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if latest.ResourceVersion != secret.ResourceVersion {
+		keep = true
+		return fmt.Errorf("secret %s was modified while it was being edited, nothing was applied. Your changes were saved to %s", name, path)
+	}
+
+	ctx = models.WithChangeInfo(ctx, models.ChangeInfo{Operation: models.OperationEdit, Source: "editor"})
+	if _, err := client.Apply(ctx, secret, models.Mutation{Set: data, Remove: models.StaleKeys(secret, data)}); err != nil {
+		return err
+	}
+
+	opts.done(p)
+	return nil
+}
+
+// editHeader explains the edited document, with err from the previous attempt
+func editHeader(name string, err error) string {
+	var b strings.Builder
+	if err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(&b, "# Error: %s\n", line)
+		}
+		b.WriteString("#\n")
+	}
+	fmt.Fprintf(&b, "# Edit the values of secret \"%s\" below and save the file to apply them.\n", name)
+	b.WriteString("# Lines beginning with '#' are ignored. Remove a line to remove its key,\n")
+	b.WriteString("# an empty file cancels the edit.\n")
+	return b.String()
+}
+
+// isBlankDocument reports whether content only has comments and blank lines
+func isBlankDocument(content []byte) bool {
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+func encodeEditDocument(format string, data map[string][]byte) ([]byte, error) {
+	if format == editFormatDotenv {
+		return dotenv.Marshal(data)
+	}
+
+	values := make(map[string]string, len(data))
+	for key, value := range data {
+		if dotenv.IsBinary(value) {
+			return nil, fmt.Errorf("key %s has a binary value, use --format %s", key, editFormatDotenv)
+		}
+		values[key] = string(value)
+	}
+	return yaml.Marshal(values)
+}
+
+func decodeEditDocument(format string, content []byte) (map[string][]byte, error) {
+	if format == editFormatDotenv {
+		return dotenv.ParseStrict(bytes.NewReader(content))
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][]byte, len(values))
+	for key, value := range values {
+		switch value := value.(type) {
+		case string:
+			data[key] = []byte(value)
+		case nil:
+			data[key] = []byte{}
+		default:
+			return nil, fmt.Errorf("value of key %s must be a string, quote it", key)
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeEditor replaces runEditor with edits that are applied to the file in
+// turn, and returns the contents the editor was opened with
+func fakeEditor(t *testing.T, edits ...func(path string)) *[]string {
+	opened := []string{}
+	original := runEditor
+	t.Cleanup(func() { runEditor = original })
+
+	runEditor = func(cmd *cobra.Command, path string) error {
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		opened = append(opened, string(content))
+
+		info, err := os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(secretFileMode), info.Mode().Perm(), "The edited file should only be readable by the user")
+
+		edits[len(opened)-1](path)
+		return nil
+	}
+	return &opened
+}
+
+func writeEdit(t *testing.T, content string) func(path string) {
+	return func(path string) {
+		assert.NoError(t, os.WriteFile(path, []byte(content), secretFileMode))
+	}
+}
+
+func TestEdit(t *testing.T) {
+	ctx := context.Background()
+
+	err := cmdExec([]string{"set", "edit-test", "A=1", "B=2", "C=3"})
+	assert.NoError(t, err)
+
+	opened := fakeEditor(t, writeEdit(t, "not a valid line\n"), writeEdit(t, "A=1\nB=changed\nD=new\n"))
+	err = cmdExec([]string{"edit", "edit-test"})
+	assert.NoError(t, err, "Editing a secret should not return an error")
+
+	assert.Len(t, *opened, 2, "The editor should be opened again after a parse error")
+	assert.Contains(t, (*opened)[0], "A=1\nB=2\nC=3\n")
+	assert.True(t, strings.HasPrefix((*opened)[1], "# Error: line 1"), "The parse error should be shown at the top")
+	assert.Contains(t, (*opened)[1], "not a valid line\n")
+
+	secret, err := secretsClient.Get(ctx, "edit-test")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"A": []byte("1"), "B": []byte("changed"), "D": []byte("new")}, secret.Data)
+
+	annotation, err := models.GetKeyAnnotation(secret, "A")
+	assert.NoError(t, err)
+	assert.Equal(t, models.OperationSet, annotation.Operation, "Unchanged keys should keep their annotation")
+	annotation, err = models.GetKeyAnnotation(secret, "B")
+	assert.NoError(t, err)
+	assert.Equal(t, models.OperationEdit, annotation.Operation)
+
+	opened = fakeEditor(t, func(path string) {})
+	err = cmdExec([]string{"edit", "edit-test"})
+	assert.NoError(t, err, "Closing the editor without changes should not return an error")
+	assert.Len(t, *opened, 1)
+
+	err = cmdExec([]string{"edit", "edit-test", "--format", "toml"})
+	assert.Error(t, err, "An unknown format should return an error")
+}
+
+func TestEditUnfixedError(t *testing.T) {
+	ctx := context.Background()
+
+	err := cmdExec([]string{"set", "edit-unfixed", "A=1"})
+	assert.NoError(t, err)
+
+	opened := fakeEditor(t, writeEdit(t, "not a valid line\n"), func(path string) {})
+	err = cmdExec([]string{"edit", "edit-unfixed"})
+	assert.ErrorContains(t, err, "edit canceled, no valid changes were saved: line 1")
+	assert.Len(t, *opened, 2, "Closing the editor without fixing the error should not open it again")
+
+	secret, err := secretsClient.Get(ctx, "edit-unfixed")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"A": []byte("1")}, secret.Data)
+}
+
+func TestEditConcurrentModification(t *testing.T) {
+	ctx := context.Background()
+
+	err := cmdExec([]string{"set", "edit-conflict", "A=1"})
+	assert.NoError(t, err)
+
+	var saved string
+	fakeEditor(t, func(path string) {
+		saved = path
+		writeEdit(t, "A=mine\n")(path)
+
+		secret, err := secretsClient.Get(ctx, "edit-conflict")
+		assert.NoError(t, err)
+		secret.Data["A"] = []byte("theirs")
+		secret.ResourceVersion = "concurrent"
+		_, err = secretsClient.Update(ctx, secret, secret.Data)
+		assert.NoError(t, err)
+	})
+
+	err = cmdExec([]string{"edit", "edit-conflict"})
+	assert.ErrorContains(t, err, "secret edit-conflict was modified while it was being edited")
+	defer os.Remove(saved)
+
+	content, err := os.ReadFile(saved)
+	assert.NoError(t, err, "The edited file should be kept")
+	assert.Contains(t, string(content), "A=mine\n")
+
+	secret, err := secretsClient.Get(ctx, "edit-conflict")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("theirs"), secret.Data["A"], "The concurrent change should not be overwritten")
+}
+
+func TestEditDocumentYAML(t *testing.T) {
+	t.Parallel()
+
+	data := map[string][]byte{"PORT": []byte("8080"), "MULTI": []byte("a\nb")}
+	content, err := encodeEditDocument(editFormatYAML, data)
+	assert.NoError(t, err)
+
+	decoded, err := decodeEditDocument(editFormatYAML, append([]byte(editHeader("s", nil)), content...))
+	assert.NoError(t, err)
+	assert.Equal(t, data, decoded)
+
+	_, err = decodeEditDocument(editFormatYAML, []byte("PORT: 8080\n"))
+	assert.EqualError(t, err, "value of key PORT must be a string, quote it")
+
+	_, err = encodeEditDocument(editFormatYAML, map[string][]byte{"BIN": {0xff, 0x00}})
+	assert.Error(t, err, "Binary values cannot be edited as yaml")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Exit codes of Kubernetes API errors, scripts can branch on them
+const (
+	exitCodeNotFound     = 3
+	exitCodeForbidden    = 4
+	exitCodeUnauthorized = 5
+	exitCodeConflict     = 6
+	exitCodeInvalid      = 7
+	exitCodeTimeout      = 8
+)
+
+// classifyError gives Kubernetes API errors a hint on how to resolve them and
+// a distinct exit code. Other errors are returned unchanged.
+func classifyError(err error) error {
+	var exitErr *exitError
+	if err == nil || errors.As(err, &exitErr) {
+		return err
+	}
+
+	var conflictErr *models.ConflictError
+	switch {
+	case errors.As(err, &conflictErr):
+		return apiError(exitCodeConflict, err, "re-run the command to review the new values")
+	case apierrors.IsNotFound(err):
+		return apiError(exitCodeNotFound, err, "check the name, --namespace and --context")
+	case apierrors.IsForbidden(err):
+		return apiError(exitCodeForbidden, err, "the identity ksec uses is not allowed to do this, check --context and --as or ask a cluster admin for access")
+	case apierrors.IsUnauthorized(err):
+		return apiError(exitCodeUnauthorized, err, "the credentials were rejected, log in to the cluster again or check --token")
+	case apierrors.IsAlreadyExists(err):
+		return apiError(exitCodeConflict, err, "use set or push to change an existing Secret")
+	case apierrors.IsConflict(err):
+		return apiError(exitCodeConflict, err, "the Secret was changed by someone else, re-run the command")
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return apiError(exitCodeInvalid, err, "the API server rejected the Secret, check the key names and values")
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), apierrors.IsTooManyRequests(err), errors.Is(err, context.DeadlineExceeded):
+		return apiError(exitCodeTimeout, err, "the API server did not respond in time, check connectivity and retry")
+	}
+	return err
+}
+
+func apiError(code int, err error, hint string) error {
+	return &exitError{code: code, err: fmt.Errorf("%w\nHint: %s", err, hint)}
+}
+
+// classifyErrors wraps the RunE of cmd and its subcommands with classifyError.
+// API errors are not caused by wrong usage, so usage is not printed for them.
+func classifyErrors(cmd *cobra.Command) {
+	if runE := cmd.RunE; runE != nil {
+		cmd.RunE = func(cmd *cobra.Command, args []string) error {
+			err := runE(cmd, args)
+			if classified := classifyError(err); classified != err {
+				cmd.SilenceUsage = true
+				return classified
+			}
+			return err
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		classifyErrors(sub)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestClassifyError(t *testing.T) {
+	t.Parallel()
+
+	resource := schema.GroupResource{Resource: "secrets"}
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{err: apierrors.NewNotFound(resource, "app"), code: exitCodeNotFound},
+		{err: apierrors.NewForbidden(resource, "app", errors.New("no access")), code: exitCodeForbidden},
+		{err: apierrors.NewUnauthorized("expired"), code: exitCodeUnauthorized},
+		{err: apierrors.NewAlreadyExists(resource, "app"), code: exitCodeConflict},
+		{err: apierrors.NewConflict(resource, "app", errors.New("stale")), code: exitCodeConflict},
+		{err: &models.ConflictError{Name: "app", Keys: []string{"key"}}, code: exitCodeConflict},
+		{err: apierrors.NewInvalid(schema.GroupKind{Kind: "Secret"}, "app", nil), code: exitCodeInvalid},
+		{err: apierrors.NewTimeoutError("slow", 1), code: exitCodeTimeout},
+		{err: fmt.Errorf("get: %w", context.DeadlineExceeded), code: exitCodeTimeout},
+		{err: errors.New("other"), code: 1},
+	}
+
+	for _, test := range tests {
+		err := classifyError(test.err)
+		assert.Equal(t, test.code, exitCode(err), "error %v", test.err)
+		assert.ErrorIs(t, err, test.err, "the original error should be kept")
+	}
+
+	assert.Nil(t, classifyError(nil))
+
+	drift := &exitError{code: exitCodeDrift, err: apierrors.NewNotFound(resource, "app")}
+	assert.Equal(t, drift, classifyError(drift), "errors with an exit code should not be reclassified")
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [secret[,secret...]] -- [command] [args...]",
+	Short: "Run a command with Secret values as environment variables",
+	Long: `Run a command with the keys of one or more Secrets added to its environment.
+
+Nothing is written to disk. When several Secrets have the same key the last
+one wins. Signals are forwarded to the command and ksec exits with its exit
+code.`,
+	Example: `  ksec exec api-creds -- ./migrate.sh
+  ksec exec api-creds,db-creds --only DB_USER,DB_PASS --prefix APP_ -- env`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: execCommand,
+}
+
+func execCommand(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	// flags of the command must not be parsed as ksec flags
+	if cmd.ArgsLenAtDash() != 1 {
+		return fmt.Errorf("separate the command from the secrets with --, e.g. ksec exec %s -- %s", args[0], strings.Join(args[1:], " "))
+	}
+
+	prefix, err := cmd.Flags().GetString("prefix")
+	if err != nil {
+		return err
+	}
+	only, err := cmd.Flags().GetStringSlice("only")
+	if err != nil {
+		return err
+	}
+	clearEnv, err := cmd.Flags().GetBool("clear-env")
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string][]byte)
+	for _, name := range strings.Split(args[0], ",") {
+		secret, err := secretsClient.Get(ctx, name)
+		if err != nil {
+			return err
+		}
+		for key, value := range secret.Data {
+			data[key] = value
+		}
+	}
+
+	if len(only) > 0 {
+		selected := make(map[string][]byte, len(only))
+		for _, key := range only {
+			value, ok := data[key]
+			if !ok {
+				return fmt.Errorf("key %s does not exist in secret %s", key, args[0])
+			}
+			selected[key] = value
+		}
+		data = selected
+	}
+
+	// an empty, non-nil environment is not inherited by the command
+	env := []string{}
+	if !clearEnv {
+		env = os.Environ()
+	}
+	for _, key := range sortedKeys(data) {
+		name := prefix + key
+		if !shellNamePattern.MatchString(name) {
+			fmt.Fprintf(os.Stderr, "Warning: skipping key %s, not a valid environment variable name\n", key)
+			continue
+		}
+		if strings.ContainsRune(string(data[key]), 0) {
+			fmt.Fprintf(os.Stderr, "Warning: skipping key %s, value contains a NUL byte\n", key)
+			continue
+		}
+		env = append(env, name+"="+string(data[key]))
+	}
+
+	return runChild(cmd, args[1], args[2:], env)
+}
+
+// runChild runs a command with env, forwarding signals to it until it exits.
+// A non-zero exit status is returned as an exitError with the same code.
+func runChild(cmd *cobra.Command, name string, args []string, env []string) error {
+	child := exec.Command(name, args...)
+	child.Env = env
+	child.Stdin = cmd.InOrStdin()
+	child.Stdout = cmd.OutOrStdout()
+	child.Stderr = cmd.ErrOrStderr()
+
+	if err := child.Start(); err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	go func() {
+		for sig := range signals {
+			_ = child.Process.Signal(sig)
+		}
+	}()
+
+	err := child.Wait()
+	signal.Stop(signals)
+	close(signals)
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+
+	code := exitErr.ExitCode()
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		code = 128 + int(status.Signal())
+	}
+
+	// the command reported its own error, only the exit code is passed on
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	return &exitError{code: code, err: err}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	t.Setenv("KSEC_TEST_INHERITED", "inherited")
+
+	out := &bytes.Buffer{}
+	rootCmd.SetOut(out)
+	defer rootCmd.SetOut(nil)
+
+	err := cmdExec([]string{"set", "exectest1", "DB_USER=app", "DB_PASS=one two", "invalid-name=x"})
+	assert.NoError(t, err)
+	err = cmdExec([]string{"set", "exectest2", "DB_PASS=override"})
+	assert.NoError(t, err)
+	out.Reset()
+
+	// runs first, pflag keeps the position of -- between executions
+	err = cmdExec([]string{"exec", "exectest1", "true"})
+	assert.Error(t, err, "The command should be separated with --")
+	out.Reset()
+
+	err = cmdExec([]string{"exec", "exectest1,exectest2", "--", "sh", "-c", `echo "$DB_USER:$DB_PASS:$KSEC_TEST_INHERITED"`})
+	assert.NoError(t, err)
+	assert.Equal(t, "app:override:inherited\n", out.String(), "Later secrets should override earlier ones")
+
+	out.Reset()
+	err = cmdExec([]string{"exec", "exectest1", "--prefix", "APP_", "--only", "DB_USER", "--clear-env", "--", "/bin/sh", "-c", `echo "$APP_DB_USER:$DB_USER:$APP_DB_PASS:$KSEC_TEST_INHERITED"`})
+	assert.NoError(t, err)
+	assert.Equal(t, "app:::\n", out.String())
+
+	err = cmdExec([]string{"exec", "exectest1", "--", "sh", "-c", "exit 3"})
+	assert.Equal(t, 3, exitCode(err), "The exit code of the command should be passed on")
+
+	err = cmdExec([]string{"exec", "exectest1", "--", "sh", "-c", "kill -TERM $$"})
+	assert.Equal(t, 128+15, exitCode(err), "A command killed by a signal should exit with 128 + the signal number")
+
+	err = cmdExec([]string{"exec", "exectest1", "--only", "MISSING", "--", "true"})
+	assert.EqualError(t, err, "key MISSING does not exist in secret exectest1")
+
+	err = cmdExec([]string{"exec", "exectest1", "--", "ksec-command-that-does-not-exist"})
+	assert.Error(t, err)
+}
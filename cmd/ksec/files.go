@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// secretFileMode is used for every file ksec writes secret values into
+const secretFileMode = 0600
+
+// writeSecretFile writes content to a temporary file next to path and renames
+// it into place, so readers never observe a partially written file.
+func writeSecretFile(path string, content []byte) (err error) {
+	// write through symlinks instead of replacing them
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf(".%s.tmp-*", filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = tmp.Chmod(secretFileMode); err != nil {
+		return err
+	}
+	if _, err = tmp.Write(content); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir flushes a directory entry after a rename, not supported on all platforms
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	defer d.Close()
+	d.Sync()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// warnIfNotGitIgnored prints a warning when path is inside a git repository
+// but would not be ignored by it. Nothing is printed when git is unavailable.
+func warnIfNotGitIgnored(path string) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+
+	root := gitRoot(filepath.Dir(abs))
+	if root == "" {
+		return
+	}
+
+	err = exec.Command("git", "-C", root, "check-ignore", "--quiet", "--", abs).Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		fmt.Fprintf(os.Stderr, "Warning: %s is not covered by .gitignore in %s, secrets could be committed by accident\n", path, root)
+	}
+}
+
+// gitRoot returns the closest parent directory of dir containing .git
+func gitRoot(dir string) string {
+	for {
+		if fileExists(filepath.Join(dir, ".git")) {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteSecretFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".env")
+
+	assert.NoError(t, os.WriteFile(path, []byte("OLD=value\n"), 0644))
+	assert.NoError(t, writeSecretFile(path, []byte("NEW=value\n")))
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "NEW=value\n", string(content))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(secretFileMode), info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files should not be left behind")
+}
+
+func TestGitRoot(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "a", "b")
+	assert.NoError(t, os.MkdirAll(nested, 0755))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0755))
+
+	assert.Equal(t, dir, gitRoot(nested))
+}
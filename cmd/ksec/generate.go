@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kanopy-platform/ksec/pkg/generate"
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+)
+
+var generateCmd = &cobra.Command{
+	Use:   "generate [secret] [key...]",
+	Short: "Set keys of a Secret to random values",
+	Long: `Set keys of a Secret to random values read from crypto/rand, creating the
+Secret when it does not exist.
+
+Passwords have at least one lowercase letter, uppercase letter, digit and
+symbol. The length of passwords and alnum values is in characters, the length
+of hex and base64 values is the number of random bytes they encode. Existing
+keys are only replaced with --force.`,
+	Example: `  ksec generate db-creds DB_PASS
+  ksec generate db-creds DB_PASS --length 24 --exclude-ambiguous
+  ksec generate app SESSION_KEY --type hex --length 64`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: generateCommand,
+}
+
+// addGeneratorFlags registers the flags describing a generator
+func addGeneratorFlags(cmd *cobra.Command) {
+	cmd.Flags().String("type", generate.TypePassword, fmt.Sprintf("Type of the random values, one of: %s", strings.Join(generate.Types(), ", ")))
+	cmd.Flags().Int("length", 0, "Length of the random values (Default: 32)")
+	cmd.Flags().Bool("no-symbols", false, "Leave symbols out of passwords")
+	cmd.Flags().Bool("exclude-ambiguous", false, "Leave out characters that are easily confused, such as l, 1, O and 0")
+}
+
+// getGenerator returns the generator described by the flags of addGeneratorFlags
+func getGenerator(cmd *cobra.Command) (*generate.Generator, error) {
+	genType, err := cmd.Flags().GetString("type")
+	if err != nil {
+		return nil, err
+	}
+	length, err := cmd.Flags().GetInt("length")
+	if err != nil {
+		return nil, err
+	}
+	noSymbols, err := cmd.Flags().GetBool("no-symbols")
+	if err != nil {
+		return nil, err
+	}
+	excludeAmbiguous, err := cmd.Flags().GetBool("exclude-ambiguous")
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := generate.New(genType, length)
+	if err != nil {
+		return nil, err
+	}
+	g.NoSymbols = noSymbols
+	g.ExcludeAmbiguous = excludeAmbiguous
+	return g, nil
+}
+
+func generateCommand(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	ctx := context.Background()
+
+	opts, err := getPlanOptions(cmd)
+	if err != nil {
+		return err
+	}
+	g, err := getGenerator(cmd)
+	if err != nil {
+		return err
+	}
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return err
+	}
+
+	secret, err := getSecretIfExists(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	values := newKeyValues()
+	for _, key := range args[1:] {
+		if err := values.generate(key, g); err != nil {
+			return err
+		}
+	}
+	if !force {
+		if err := values.checkOverwrite(secret); err != nil {
+			return err
+		}
+	}
+
+	secretType := v1.SecretTypeOpaque
+	if secret != nil && secret.Type != "" {
+		secretType = secret.Type
+	}
+	return setValues(ctx, opts, name, secret, secretType, values, models.OperationGenerate)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	ctx := context.Background()
+
+	err := cmdExec([]string{"generate", "generated", "SESSION_KEY", "--type", "hex", "--length", "16"})
+	assert.NoError(t, err, "Generating a value should not return an error")
+
+	secret, err := secretsClient.Get(ctx, "generated")
+	assert.NoError(t, err)
+	assert.Len(t, secret.Data["SESSION_KEY"], 32)
+	value := secret.Data["SESSION_KEY"]
+
+	annotation, err := models.GetKeyAnnotation(secret, "SESSION_KEY")
+	assert.NoError(t, err)
+	assert.Equal(t, models.OperationGenerate, annotation.Operation)
+	assert.Equal(t, "hex:16", annotation.Generator)
+
+	err = cmdExec([]string{"generate", "generated", "SESSION_KEY"})
+	assert.EqualError(t, err, "key SESSION_KEY already exists in secret generated, use --force to replace it with a generated value")
+
+	err = cmdExec([]string{"generate", "generated", "SESSION_KEY", "--force", "--exclude-ambiguous"})
+	assert.NoError(t, err, "Replacing a value with --force should not return an error")
+
+	secret, err = secretsClient.Get(ctx, "generated")
+	assert.NoError(t, err)
+	assert.NotEqual(t, value, secret.Data["SESSION_KEY"])
+	annotation, err = models.GetKeyAnnotation(secret, "SESSION_KEY")
+	assert.NoError(t, err)
+	assert.Equal(t, "password:32:no-ambiguous", annotation.Generator)
+
+	err = cmdExec([]string{"generate", "generated", "PIN", "--type", "pin"})
+	assert.Error(t, err, "An unknown generator type should return an error")
+}
+
+func TestSetGenerated(t *testing.T) {
+	ctx := context.Background()
+
+	err := cmdExec([]string{"set", "set-generated", "DB_PASS=@gen:password:24", "DB_USER=app"})
+	assert.NoError(t, err, "Setting a generated value should not return an error")
+
+	secret, err := secretsClient.Get(ctx, "set-generated")
+	assert.NoError(t, err)
+	assert.Len(t, secret.Data["DB_PASS"], 24)
+
+	annotation, err := models.GetKeyAnnotation(secret, "DB_PASS")
+	assert.NoError(t, err)
+	assert.Equal(t, models.OperationSet, annotation.Operation)
+	assert.Equal(t, "password:24", annotation.Generator)
+	annotation, err = models.GetKeyAnnotation(secret, "DB_USER")
+	assert.NoError(t, err)
+	assert.Empty(t, annotation.Generator)
+
+	err = cmdExec([]string{"set", "set-generated", "DB_PASS=@gen:password:24"})
+	assert.ErrorContains(t, err, "key DB_PASS already exists in secret set-generated")
+
+	err = cmdExec([]string{"set", "set-generated", "DB_PASS=@gen:password:24", "--force"})
+	assert.NoError(t, err, "Replacing a generated value with --force should not return an error")
+
+	err = cmdExec([]string{"set", "set-generated", "DB_PASS=@gen:pin"})
+	assert.ErrorContains(t, err, "key DB_PASS: unknown generator \"pin\"")
+}
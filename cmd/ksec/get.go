@@ -2,11 +2,12 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/kanopy-platform/ksec/pkg/models"
 	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
 )
 
 var getCmd = &cobra.Command{
@@ -16,11 +17,29 @@ var getCmd = &cobra.Command{
 	RunE:  getCommand,
 }
 
+var getOutputFormats = []string{outputTable, outputJSON, outputYAML, outputEnv, outputShell, outputName}
+
 func getCommand(cmd *cobra.Command, args []string) error {
 	secretName := args[0]
 	ctx := context.Background()
 
-	if len(args) > 1 {
+	format, err := getOutputFormat(cmd, getOutputFormats...)
+	if err != nil {
+		return err
+	}
+
+	// values of a single key are always printed so they can be piped
+	reveal, err := cmd.Flags().GetBool("reveal")
+	if err != nil {
+		return err
+	}
+	reveal = reveal || len(args) > 1
+
+	if (format == outputEnv || format == outputShell) && !reveal {
+		return fmt.Errorf("output format %s prints secret values, use --reveal", format)
+	}
+
+	if len(args) > 1 && format == outputTable {
 		value, err := secretsClient.GetKey(ctx, secretName, args[1])
 		if err != nil {
 			return err
@@ -35,38 +54,105 @@ func getCommand(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	var lines []string
+	// structured output of a single key keeps the schema of the whole Secret
+	if len(args) > 1 {
+		value, ok := secret.Data[args[1]]
+		if !ok {
+			return fmt.Errorf("secret key %s does not exist", args[1])
+		}
+		secret.Data = map[string][]byte{args[1]: value}
+	}
+
+	switch format {
+	case outputJSON, outputYAML:
+		out, err := newSecretOutput(secret, reveal)
+		if err != nil {
+			return err
+		}
+		return printStructured(format, out)
+	case outputEnv:
+		return printEnv(secret.Data)
+	case outputShell:
+		printShell(secret.Data)
+		return nil
+	case outputName:
+		for _, key := range sortedKeys(secret.Data) {
+			fmt.Println(key)
+		}
+		return nil
+	}
+
 	verbose, err := cmd.Flags().GetBool("verbose")
 	if err != nil {
 		return err
 	}
+	return printSecretTable(secret, verbose, reveal)
+}
 
-	if verbose {
-		for key, value := range secret.Data {
-			rawAnnotation := secret.Annotations[fmt.Sprintf("ksec.io/%s", key)]
-
-			var jsonAnnotation []byte
-			if rawAnnotation == "" {
-				jsonAnnotation = []byte(`{"updatedBy": "", "lastUpdated": ""}`)
-			} else {
-				jsonAnnotation = []byte(rawAnnotation)
-			}
+func printSecretTable(secret *v1.Secret, verbose, reveal bool) error {
+	var lines []string
 
-			annotation := models.KeyAnnotation{}
-			if err := json.Unmarshal(jsonAnnotation, &annotation); err != nil {
+	display := func(key string) string {
+		if reveal {
+			return string(secret.Data[key])
+		}
+		return maskValue(secret.Data[key])
+	}
+
+	if verbose {
+		for _, key := range sortedKeys(secret.Data) {
+			annotation, err := models.GetKeyAnnotation(secret, key)
+			if err != nil {
 				return err
 			}
+			if annotation == nil {
+				annotation = &models.KeyAnnotation{}
+			}
+
 			lines = append(lines, fmt.Sprintf("Key:\t%s", key))
-			lines = append(lines, fmt.Sprintf("Value:\t%s", value))
+			lines = append(lines, fmt.Sprintf("Value:\t%s", display(key)))
 			lines = append(lines, fmt.Sprintf("User:\t%s", annotation.UpdatedBy))
+			if len(annotation.Groups) > 0 {
+				lines = append(lines, fmt.Sprintf("Groups:\t%s", strings.Join(annotation.Groups, ", ")))
+			}
+			if annotation.Operation != "" {
+				lines = append(lines, fmt.Sprintf("Operation:\t%s", annotation.Operation))
+			}
+			if annotation.Source != "" {
+				lines = append(lines, fmt.Sprintf("Source:\t%s", annotation.Source))
+			}
+			if annotation.Generator != "" {
+				lines = append(lines, fmt.Sprintf("Generator:\t%s", annotation.Generator))
+			}
+			if annotation.RotatedAt != "" {
+				lines = append(lines, fmt.Sprintf("Rotated:\t%s by %s", annotation.RotatedAt, annotation.RotatedBy))
+			}
+			if annotation.Hostname != "" {
+				lines = append(lines, fmt.Sprintf("Hostname:\t%s", annotation.Hostname))
+			}
+			if annotation.Version != "" {
+				lines = append(lines, fmt.Sprintf("Version:\t%s", annotation.Version))
+			}
+			if annotation.Fingerprint != "" {
+				lines = append(lines, fmt.Sprintf("Fingerprint:\t%s", fingerprintStatus(annotation, secret.Data[key])))
+			}
 			lines = append(lines, fmt.Sprintf("Updated:\t%s\n", annotation.LastUpdated))
 		}
 	} else {
 		lines = append(lines, "KEY\tVALUE")
-		for key, value := range secret.Data {
-			lines = append(lines, fmt.Sprintf("%s\t%s", key, value))
+		for _, key := range sortedKeys(secret.Data) {
+			lines = append(lines, fmt.Sprintf("%s\t%s", key, display(key)))
 		}
 	}
 	outputTabular(lines)
 	return nil
 }
+
+// fingerprintStatus tells whether value is still the one recorded in the
+// fingerprint of annotation
+func fingerprintStatus(annotation *models.KeyAnnotation, value []byte) string {
+	if annotation.MatchesValue(value) {
+		return "matches the current value"
+	}
+	return "does not match, the value was changed without ksec"
+}
@@ -17,9 +17,16 @@ var listCmd = &cobra.Command{
 	RunE:    listCommand,
 }
 
+var listOutputFormats = []string{outputTable, outputJSON, outputYAML, outputName}
+
 func listCommand(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	format, err := getOutputFormat(cmd, listOutputFormats...)
+	if err != nil {
+		return err
+	}
+
 	secrets, err := secretsClient.List(ctx)
 	if err != nil {
 		return err
@@ -29,18 +36,38 @@ func listCommand(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+
+	var items []v1.Secret
+	for _, secret := range secrets.Items {
+		if all || secret.Type == v1.SecretTypeOpaque {
+			items = append(items, secret)
+		}
+	}
+
+	switch format {
+	case outputJSON, outputYAML:
+		out := secretListOutput{Items: []secretSummaryOutput{}}
+		for i := range items {
+			out.Items = append(out.Items, newSecretSummaryOutput(&items[i]))
+		}
+		return printStructured(format, out)
+	case outputName:
+		for _, secret := range items {
+			fmt.Println(secret.Name)
+		}
+		return nil
+	}
+
 	if all {
 		lines := []string{"NAME\tTYPE"}
-		for _, secret := range secrets.Items {
+		for _, secret := range items {
 			lines = append(lines, fmt.Sprintf("%s\t%s", secret.Name, secret.Type))
 		}
 		outputTabular(lines)
 	} else {
 		fmt.Println("NAME")
-		for _, secret := range secrets.Items {
-			if secret.Type == v1.SecretTypeOpaque {
-				fmt.Println(secret.Name)
-			}
+		for _, secret := range items {
+			fmt.Println(secret.Name)
 		}
 	}
 	return nil
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	homedir "github.com/mitchellh/go-homedir"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
@@ -16,6 +17,7 @@ import (
 )
 
 var cfgFile string
+var clientOptions = &models.ClientOptions{}
 var secretsClient *models.SecretsClient
 
 func initRootCmd(rootCmd *cobra.Command) {
@@ -24,30 +26,115 @@ func initRootCmd(rootCmd *cobra.Command) {
 	// global options
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (Default: $HOME/.ksec.yaml)")
 	rootCmd.PersistentFlags().StringP("namespace", "n", "", "Operate in a specific NAMESPACE (Default: current kubeconfig namespace)")
+	rootCmd.PersistentFlags().String("context", "", "Use a specific kubeconfig CONTEXT (Default: current kubeconfig context)")
+	rootCmd.PersistentFlags().String("kubeconfig", "", "Path to the kubeconfig file (Default: $KUBECONFIG or $HOME/.kube/config)")
+	rootCmd.PersistentFlags().String("server", "", "Address of the Kubernetes API server (Default: kubeconfig or in-cluster configuration)")
+	rootCmd.PersistentFlags().String("token", "", "Bearer token for authentication to the API server")
+	rootCmd.PersistentFlags().String("certificate-authority", "", "Path to a CA certificate file for the API server")
+	rootCmd.PersistentFlags().String("as", "", "Username to impersonate for the operation")
+	rootCmd.PersistentFlags().StringSlice("as-group", []string{}, "Group to impersonate for the operation, can be repeated")
+	rootCmd.PersistentFlags().String("write-mode", models.WriteModeUpdate, fmt.Sprintf("How changes are written to existing Secrets, one of: %s", strings.Join(models.WriteModes, ", ")))
 
 	// setup viper config
 	viper.BindPFlags(rootCmd.PersistentFlags())
 
-	// subcommands without extra options
+	// subcommands with extra options
 	rootCmd.AddCommand(createCmd)
-	rootCmd.AddCommand(pullCmd)
-	rootCmd.AddCommand(pushCmd)
-	rootCmd.AddCommand(setCmd)
-	rootCmd.AddCommand(unsetCmd)
+	addTypeFlag(createCmd)
 
-	// subcommands with extra options
 	rootCmd.AddCommand(getCmd)
 	getCmd.Flags().BoolP("verbose", "v", false, "Show extra metadata")
+	getCmd.Flags().Bool("reveal", false, "Show values instead of masking them")
+	addOutputFlag(getCmd, getOutputFormats...)
 
 	rootCmd.AddCommand(deleteCmd)
 	deleteCmd.Flags().BoolP("yes", "y", false, "do not ask for confirmation")
 
+	rootCmd.AddCommand(pullCmd)
+	pullCmd.Flags().BoolP("force", "f", false, "Overwrite the file if it already exists")
+	pullCmd.Flags().Bool("merge", false, "Update an existing file in place, keeping comments, ordering and local-only keys")
+	pullCmd.Flags().Bool("comment-missing", false, "Comment out keys that are not in the Secret (requires --merge)")
+	pullCmd.MarkFlagsMutuallyExclusive("force", "merge")
+
+	rootCmd.AddCommand(pushCmd)
+	pushCmd.Flags().Bool("strict", false, "Fail on malformed lines instead of skipping them")
+	pushCmd.Flags().Bool("prune", false, "Remove keys from the Secret that are not in the file")
+	addPlanFlags(pushCmd)
+	addTypeFlag(pushCmd)
+
+	rootCmd.AddCommand(rotateCmd)
+	rotateCmd.Flags().String("generator", "", "Generate the new value, e.g. password:32 (Default: the generator recorded for the key)")
+	rotateCmd.Flags().String("value-from", "", "Read the new value from a file, or from stdin with -")
+	rotateCmd.Flags().String("suffix", "_PREVIOUS", "Suffix of the key keeping the previous value")
+	rotateCmd.Flags().Bool("finish", false, "Remove the previous value once all consumers use the new one")
+	rotateCmd.Flags().Bool("force", false, "Start a new rotation even if the previous one was not finished")
+	rotateCmd.MarkFlagsMutuallyExclusive("generator", "value-from")
+	addPlanFlags(rotateCmd)
+
+	rootCmd.AddCommand(setCmd)
+	setCmd.Flags().StringSlice("prompt", []string{}, "Read the value of a key from the terminal without echoing it, can be repeated")
+	setCmd.Flags().Bool("force", false, "Replace existing keys with generated values")
+	addPlanFlags(setCmd)
+	addTypeFlag(setCmd)
+
+	rootCmd.AddCommand(unsetCmd)
+	addPlanFlags(unsetCmd)
+
+	rootCmd.AddCommand(generateCmd)
+	addGeneratorFlags(generateCmd)
+	generateCmd.Flags().Bool("force", false, "Replace keys that already exist")
+	addPlanFlags(generateCmd)
+
+	rootCmd.AddCommand(editCmd)
+	editCmd.Flags().String("format", editFormatDotenv, `Format of the edited document, "dotenv" or "yaml"`)
+	addPlanFlags(editCmd)
+
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().Bool("show-values", false, "Show values instead of their SHA-256 digest")
+
+	rootCmd.AddCommand(execCmd)
+	execCmd.Flags().String("prefix", "", "Prefix added to the environment variable names")
+	execCmd.Flags().StringSlice("only", []string{}, "Only add these keys to the environment")
+	execCmd.Flags().Bool("clear-env", false, "Do not pass the environment of ksec on to the command")
+
 	rootCmd.AddCommand(listCmd)
 	listCmd.Flags().BoolP("all", "a", false, "Show all secrets (Default: Opaque only)")
+	addOutputFlag(listCmd, listOutputFormats...)
+
+	rootCmd.AddCommand(tlsCmd)
+	tlsCmd.AddCommand(tlsSetCmd)
+	tlsSetCmd.Flags().String("cert", "", "PEM file with the certificate followed by its intermediates")
+	tlsSetCmd.Flags().String("key", "", "PEM file with the private key of the certificate")
+	tlsSetCmd.Flags().String("ca", "", "PEM file with the CA certificate the chain must verify against, stored as ca.crt (an existing ca.crt is removed without it)")
+	tlsSetCmd.MarkFlagRequired("cert")
+	tlsSetCmd.MarkFlagRequired("key")
+	addPlanFlags(tlsSetCmd)
+	tlsCmd.AddCommand(tlsInspectCmd)
+	addOutputFlag(tlsInspectCmd, tlsInspectOutputFormats...)
+
+	rootCmd.AddCommand(registryCmd)
+	registryCmd.AddCommand(registryAddCmd)
+	registryAddCmd.Flags().String("registry", "", "Registry server, e.g. ghcr.io")
+	registryAddCmd.Flags().StringP("username", "u", "", "Registry username")
+	registryAddCmd.Flags().StringP("password", "p", "", "Registry password, prefer --password-stdin")
+	registryAddCmd.Flags().Bool("password-stdin", false, "Read the password from stdin")
+	registryAddCmd.Flags().String("email", "", "Email of the registry account")
+	registryAddCmd.Flags().String("attach-to", "", "Add the Secret to the imagePullSecrets of a service account, e.g. serviceaccount/default")
+	registryAddCmd.MarkFlagRequired("registry")
+	registryAddCmd.MarkFlagsMutuallyExclusive("password", "password-stdin")
+	addPlanFlags(registryAddCmd)
+	registryCmd.AddCommand(registryRemoveCmd)
+	registryRemoveCmd.Flags().String("registry", "", "Registry server to remove")
+	registryRemoveCmd.MarkFlagRequired("registry")
+	addPlanFlags(registryRemoveCmd)
+	registryCmd.AddCommand(registryListCmd)
+	addOutputFlag(registryListCmd, registryListOutputFormats...)
 
 	rootCmd.AddCommand(completionCmd)
 	completionCmd.AddCommand(bashCompletionCmd)
 	completionCmd.AddCommand(zshCompletionCmd)
+
+	classifyErrors(rootCmd)
 }
 
 func NewRootCmd() *cobra.Command {
@@ -55,19 +142,15 @@ func NewRootCmd() *cobra.Command {
 		Use:     "ksec",
 		Short:   "A tool for managing Kubernetes Secret data",
 		Version: version.Get().Version,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			var err error
-			namespace := viper.GetString("namespace")
-
-			// sets namespace when used as a helm plugin since helm hijacks the "--namespace" flag.
-			if namespace == "" && os.Getenv("HELM_NAMESPACE") != "" {
-				namespace = os.Getenv("HELM_NAMESPACE")
-			}
+			clientOptions = newClientOptions()
 
-			secretsClient, err = models.NewSecretsClient(namespace)
+			secretsClient, err = models.NewSecretsClient(clientOptions)
 			if err != nil {
-				log.Fatal(err.Error())
+				cmd.SilenceUsage = true
 			}
+			return err
 		},
 	}
 
@@ -75,6 +158,47 @@ func NewRootCmd() *cobra.Command {
 	return rootCmd
 }
 
+// newClientOptions reads the cluster connection settings from flags, config
+// and environment. When used as a helm plugin the settings helm hijacked from
+// the command line are read from the HELM_* environment variables instead.
+func newClientOptions() *models.ClientOptions {
+	opts := &models.ClientOptions{
+		Namespace:            viper.GetString("namespace"),
+		Context:              viper.GetString("context"),
+		Kubeconfig:           viper.GetString("kubeconfig"),
+		Server:               viper.GetString("server"),
+		Token:                viper.GetString("token"),
+		CertificateAuthority: viper.GetString("certificate-authority"),
+		Impersonate:          viper.GetString("as"),
+		ImpersonateGroups:    viper.GetStringSlice("as-group"),
+		WriteMode:            viper.GetString("write-mode"),
+	}
+
+	if opts.Namespace == "" {
+		opts.Namespace = os.Getenv("HELM_NAMESPACE")
+	}
+	if opts.Context == "" {
+		opts.Context = os.Getenv("HELM_KUBECONTEXT")
+	}
+	if opts.Server == "" {
+		opts.Server = os.Getenv("HELM_KUBEAPISERVER")
+	}
+	if opts.Token == "" {
+		opts.Token = os.Getenv("HELM_KUBETOKEN")
+	}
+	if opts.CertificateAuthority == "" {
+		opts.CertificateAuthority = os.Getenv("HELM_KUBECAFILE")
+	}
+	if opts.Impersonate == "" {
+		opts.Impersonate = os.Getenv("HELM_KUBEASUSER")
+	}
+	if len(opts.ImpersonateGroups) == 0 && os.Getenv("HELM_KUBEASGROUPS") != "" {
+		opts.ImpersonateGroups = strings.Split(os.Getenv("HELM_KUBEASGROUPS"), ",")
+	}
+
+	return opts
+}
+
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
 	if cfgFile != "" {
@@ -93,16 +217,18 @@ func initConfig() {
 	}
 
 	viper.SetEnvPrefix("KSEC")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	viper.AutomaticEnv() // read in environment variables that match
 
-	// If a config file is found, read it in.
+	// If a config file is found, read it in. The notice goes to stderr so the
+	// output of get can still be parsed or evaluated.
 	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
 
 func main() {
-	if err := NewRootCmd().Execute(); err != nil {
-		os.Exit(1)
+	if cmd, err := NewRootCmd().ExecuteC(); err != nil {
+		os.Exit(commandExitCode(cmd, err))
 	}
 }
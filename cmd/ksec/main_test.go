@@ -3,13 +3,16 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"os"
-	"strings"
+	"path/filepath"
 	"testing"
 
 	"github.com/kanopy-platform/ksec/pkg/models"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
 )
 
 // mock rootCmd
@@ -27,10 +30,26 @@ func TestMain(m *testing.M) {
 }
 
 func cmdExec(args []string) error {
+	resetFlags(rootCmd)
 	rootCmd.SetArgs(args)
 	return rootCmd.Execute()
 }
 
+// resetFlags restores flag defaults since the mock rootCmd is reused across tests
+func resetFlags(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			slice.Replace([]string{})
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
 // tests
 func TestCreateSecret(t *testing.T) {
 	ctx := context.Background()
@@ -70,7 +89,7 @@ func TestDeleteSecret(t *testing.T) {
 
 	err = cmdExec([]string{"get", "test"})
 	assert.Error(t, err, "Getting deleted secret should return an error")
-	assert.True(t, strings.HasSuffix(err.Error(), "not found"), "Error should indicate that the secret was not found")
+	assert.Contains(t, err.Error(), "not found", "Error should indicate that the secret was not found")
 }
 
 func TestPushSecret(t *testing.T) {
@@ -108,7 +127,14 @@ func TestPullSecret(t *testing.T) {
 	assert.NoError(t, err, "Setting secret should not return an error")
 
 	err = cmdExec([]string{"pull", "pulltest", tempfile.Name()})
-	assert.NoError(t, err, "Pulling secret should not return an error")
+	assert.Error(t, err, "Pulling into an existing file should return an error")
+
+	err = cmdExec([]string{"pull", "pulltest", tempfile.Name(), "--force"})
+	assert.NoError(t, err, "Pulling secret with --force should not return an error")
+
+	info, err := os.Stat(tempfile.Name())
+	assert.NoError(t, err, "Stat of pulled file should not return an error")
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "Pulled file should only be readable by the owner")
 
 	file, err := os.Open(tempfile.Name())
 	assert.NoError(t, err, "Opening temp file should not return an error")
@@ -120,3 +146,248 @@ func TestPullSecret(t *testing.T) {
 
 	assert.Equal(t, "ENV_VAR=secret", string(line), "File should contain the pulled secret contents")
 }
+
+func TestPullPushRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	data := map[string][]byte{
+		"PLAIN":   []byte("value"),
+		"SPACES":  []byte("  leading and trailing  "),
+		"COMMENT": []byte("value # not a comment"),
+		"QUOTES":  []byte(`it's "quoted"`),
+		"PEM":     []byte("-----BEGIN KEY-----\nabc\n-----END KEY-----\n"),
+		"BINARY":  {0x00, 0x01, 0xfe, 0xff},
+	}
+
+	_, err := secretsClient.Upsert(ctx, "roundtrip", data)
+	assert.NoError(t, err, "Creating secret should not return an error")
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, ".env")
+
+	err = cmdExec([]string{"pull", "roundtrip", file})
+	assert.NoError(t, err, "Pulling secret should not return an error")
+
+	first, err := os.ReadFile(file)
+	assert.NoError(t, err, "Reading pulled file should not return an error")
+
+	err = cmdExec([]string{"push", "--strict", file, "roundtrip-copy"})
+	assert.NoError(t, err, "Pushing pulled file should not return an error")
+
+	secret, err := secretsClient.Get(ctx, "roundtrip-copy")
+	assert.NoError(t, err, "Getting pushed secret should not return an error")
+	assert.Equal(t, data, secret.Data, "Pushed data should match the original secret")
+
+	err = cmdExec([]string{"pull", "roundtrip-copy", file, "--force"})
+	assert.NoError(t, err, "Pulling secret again should not return an error")
+
+	second, err := os.ReadFile(file)
+	assert.NoError(t, err, "Reading pulled file should not return an error")
+	assert.Equal(t, string(first), string(second), "Pulled output should be deterministic")
+}
+
+func TestPullMerge(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".env")
+	err := os.WriteFile(file, []byte("# local settings\nDEBUG=true\nTOKEN=old\n"), 0600)
+	assert.NoError(t, err, "Writing existing file should not return an error")
+
+	err = cmdExec([]string{"set", "mergetest", "TOKEN=new", "API_KEY=abc"})
+	assert.NoError(t, err, "Setting secret should not return an error")
+
+	err = cmdExec([]string{"pull", "mergetest", file, "--merge"})
+	assert.NoError(t, err, "Pulling secret with --merge should not return an error")
+
+	content, err := os.ReadFile(file)
+	assert.NoError(t, err, "Reading merged file should not return an error")
+	assert.Equal(t, "# local settings\nDEBUG=true\nTOKEN=new\n\n# Added by ksec\nAPI_KEY=abc\n", string(content))
+
+	err = cmdExec([]string{"pull", "mergetest", file, "--merge", "--comment-missing"})
+	assert.NoError(t, err, "Pulling secret with --comment-missing should not return an error")
+
+	content, err = os.ReadFile(file)
+	assert.NoError(t, err, "Reading merged file should not return an error")
+	assert.Contains(t, string(content), "\n# DEBUG=true\n", "Keys missing from the secret should be commented out")
+
+	err = cmdExec([]string{"pull", "mergetest", file, "--merge", "--force"})
+	assert.Error(t, err, "--merge and --force should be mutually exclusive")
+}
+
+func TestPushPrune(t *testing.T) {
+	ctx := context.Background()
+
+	err := cmdExec([]string{"set", "prunetest", "KEEP=old", "STALE=value"})
+	assert.NoError(t, err, "Setting secret should not return an error")
+
+	file := filepath.Join(t.TempDir(), ".env")
+	err = os.WriteFile(file, []byte("KEEP=new\nADDED=value\n"), 0600)
+	assert.NoError(t, err, "Writing file should not return an error")
+
+	err = cmdExec([]string{"push", file, "prunetest", "--prune"})
+	assert.EqualError(t, err, "the changes to secret prunetest need a confirmation and stdin is not a terminal, use --yes to apply them")
+	secret, err := secretsClient.Get(ctx, "prunetest")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("old"), secret.Data["KEEP"], "Nothing should be pushed without the confirmation")
+
+	err = cmdExec([]string{"push", file, "prunetest", "--prune", "--yes"})
+	assert.NoError(t, err, "Pushing with --prune should not return an error")
+
+	secret, err = secretsClient.Get(ctx, "prunetest")
+	assert.NoError(t, err, "Getting pruned secret should not return an error")
+	assert.Equal(t, map[string][]byte{"KEEP": []byte("new"), "ADDED": []byte("value")}, secret.Data)
+	assert.NotContains(t, secret.Annotations, "ksec.io/STALE", "Annotations of pruned keys should be removed")
+
+	err = cmdExec([]string{"push", file, "prunenew", "--prune", "--yes"})
+	assert.NoError(t, err, "Pushing with --prune to a new secret should not return an error")
+}
+
+func TestDryRun(t *testing.T) {
+	ctx := context.Background()
+
+	err := cmdExec([]string{"set", "dryruntest", "KEY=value"})
+	assert.NoError(t, err, "Setting secret should not return an error")
+
+	err = cmdExec([]string{"set", "dryruntest", "KEY=changed", "--dry-run"})
+	assert.NoError(t, err, "Client dry run of set should not return an error")
+
+	err = cmdExec([]string{"unset", "dryruntest", "KEY", "--dry-run=client"})
+	assert.NoError(t, err, "Client dry run of unset should not return an error")
+
+	secret, err := secretsClient.Get(ctx, "dryruntest")
+	assert.NoError(t, err, "Getting secret should not return an error")
+	assert.Equal(t, "value", string(secret.Data["KEY"]), "Dry run should not change the secret")
+
+	err = cmdExec([]string{"set", "dryrunnew", "KEY=value", "--dry-run=client"})
+	assert.NoError(t, err, "Client dry run of set should not return an error")
+
+	_, err = secretsClient.Get(ctx, "dryrunnew")
+	assert.Error(t, err, "Dry run should not create the secret")
+
+	err = cmdExec([]string{"set", "dryruntest", "KEY=changed", "--dry-run=invalid"})
+	assert.Error(t, err, "Invalid dry run mode should return an error")
+}
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := secretsClient.Upsert(ctx, "difftest", map[string][]byte{"SAME": []byte("1"), "CHANGED": []byte("a")})
+	assert.NoError(t, err, "Creating secret should not return an error")
+
+	_, err = secretsClient.ForNamespace("prod").Upsert(ctx, "difftest", map[string][]byte{"SAME": []byte("1"), "CHANGED": []byte("b")})
+	assert.NoError(t, err, "Creating secret in another namespace should not return an error")
+
+	err = cmdExec([]string{"diff", "difftest", "default/difftest"})
+	assert.NoError(t, err, "Identical secrets should not return an error")
+
+	err = cmdExec([]string{"diff", "difftest", "prod/difftest"})
+	assert.Error(t, err, "Drift should return an error")
+	assert.Equal(t, exitCodeDrift, exitCode(err), "Drift should exit with code 1")
+
+	file := filepath.Join(t.TempDir(), ".env")
+	err = os.WriteFile(file, []byte("SAME=1\nCHANGED=a\n"), 0600)
+	assert.NoError(t, err, "Writing file should not return an error")
+
+	err = cmdExec([]string{"diff", file, "difftest"})
+	assert.NoError(t, err, "File matching the secret should not return an error")
+
+	err = cmdExec([]string{"diff", file, "doesnotexist"})
+	assert.Equal(t, exitCodeDiffError, exitCode(err), "Missing secret should exit with code 2")
+}
+
+func TestDiffErrorExitCodes(t *testing.T) {
+	resetFlags(rootCmd)
+	rootCmd.SetArgs([]string{"diff", "only-one"})
+	cmd, err := rootCmd.ExecuteC()
+	assert.Equal(t, exitCodeDiffError, commandExitCode(cmd, err), "Invalid arguments of diff should exit with code 2")
+
+	setupErr := errors.New("invalid configuration: no configuration has been provided")
+	assert.Equal(t, exitCodeDiffError, commandExitCode(diffCmd, setupErr), "A failed client setup should exit with code 2 for diff")
+	assert.Equal(t, 1, commandExitCode(getCmd, setupErr))
+	assert.Equal(t, exitCodeDrift, commandExitCode(diffCmd, &exitError{code: exitCodeDrift, err: setupErr}))
+}
+
+func TestOutputFormats(t *testing.T) {
+	err := cmdExec([]string{"set", "outputtest", "KEY=value", "key.with.dots=value"})
+	assert.NoError(t, err, "Setting secret should not return an error")
+
+	for _, format := range getOutputFormats {
+		err = cmdExec([]string{"get", "outputtest", "-o", format, "--reveal"})
+		assert.NoError(t, err, "Getting secret as %s should not return an error", format)
+	}
+
+	err = cmdExec([]string{"get", "outputtest", "-o", "env"})
+	assert.Error(t, err, "Printing values as env without --reveal should return an error")
+
+	err = cmdExec([]string{"get", "outputtest", "-o", "json"})
+	assert.NoError(t, err, "Getting masked secret as json should not return an error")
+
+	err = cmdExec([]string{"get", "outputtest", "KEY", "-o", "json"})
+	assert.NoError(t, err, "Getting a single key as json should not return an error")
+
+	err = cmdExec([]string{"get", "outputtest", "MISSING", "-o", "json"})
+	assert.Error(t, err, "Getting a missing key as json should return an error")
+
+	for _, format := range listOutputFormats {
+		err = cmdExec([]string{"list", "-o", format})
+		assert.NoError(t, err, "Listing secrets as %s should not return an error", format)
+	}
+
+	err = cmdExec([]string{"list", "-o", "env"})
+	assert.Error(t, err, "Unsupported list output format should return an error")
+}
+
+func TestNewClientOptionsHelmEnv(t *testing.T) {
+	t.Setenv("HELM_NAMESPACE", "helm-namespace")
+	t.Setenv("HELM_KUBECONTEXT", "helm-context")
+	t.Setenv("HELM_KUBEAPISERVER", "https://helm.example.com")
+	t.Setenv("HELM_KUBEASUSER", "jane")
+	t.Setenv("HELM_KUBEASGROUPS", "admins,devs")
+	t.Setenv("HELM_KUBETOKEN", "token")
+	t.Setenv("HELM_KUBECAFILE", "/path/to/ca.crt")
+
+	opts := newClientOptions()
+	assert.Equal(t, &models.ClientOptions{
+		Namespace:            "helm-namespace",
+		Context:              "helm-context",
+		Server:               "https://helm.example.com",
+		Token:                "token",
+		CertificateAuthority: "/path/to/ca.crt",
+		Impersonate:          "jane",
+		ImpersonateGroups:    []string{"admins", "devs"},
+		WriteMode:            models.WriteModeUpdate,
+	}, opts)
+
+	t.Setenv("KSEC_CONTEXT", "ksec-context")
+	assert.Equal(t, "ksec-context", newClientOptions().Context, "ksec settings should take precedence over helm")
+}
+
+func TestAPIErrorExitCode(t *testing.T) {
+	err := cmdExec([]string{"get", "doesnotexist"})
+	assert.Error(t, err, "Getting a missing secret should return an error")
+	assert.Equal(t, exitCodeNotFound, exitCode(err), "A missing secret should exit with the NotFound code")
+	assert.Contains(t, err.Error(), "Hint: check the name")
+}
+
+func TestSecretType(t *testing.T) {
+	ctx := context.Background()
+
+	err := cmdExec([]string{"set", "typetest", "username=admin", "--type", "tls"})
+	assert.Error(t, err, "Missing keys of the secret type should return an error")
+
+	err = cmdExec([]string{"set", "typetest", "username=admin", "--type", "basic-auth"})
+	assert.NoError(t, err, "Setting a basic-auth secret should not return an error")
+
+	secret, err := secretsClient.Get(ctx, "typetest")
+	assert.NoError(t, err)
+	assert.Equal(t, v1.SecretTypeBasicAuth, secret.Type)
+
+	err = cmdExec([]string{"set", "typetest", "password=secret"})
+	assert.NoError(t, err, "Setting keys without --type should keep the existing type")
+
+	err = cmdExec([]string{"set", "typetest", "password=other", "--type", "opaque"})
+	assert.EqualError(t, err, "secret typetest has type kubernetes.io/basic-auth, refusing to change it to Opaque (delete and create it again to change the type)")
+
+	err = cmdExec([]string{"create", "typetest-tls", "--type", "tls"})
+	assert.EqualError(t, err, "kubernetes.io/tls secrets cannot be created without keys, use: ksec tls set typetest-tls --cert CERT --key KEY")
+
+	err = cmdExec([]string{"create", "typetest-opaque", "--type", "opaque"})
+	assert.NoError(t, err, "Creating an empty opaque secret should not return an error")
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kanopy-platform/ksec/pkg/dotenv"
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputEnv   = "env"
+	outputShell = "shell"
+	outputName  = "name"
+
+	encodingBase64 = "base64"
+)
+
+// shellNamePattern matches keys that are valid shell variable names
+var shellNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// secretOutput is the schema of a Secret in json and yaml output
+type secretOutput struct {
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace"`
+	Type      string      `json:"type"`
+	Keys      []keyOutput `json:"keys"`
+}
+
+// keyOutput is the schema of a Secret key in json and yaml output. Value and
+// its unsalted SHA256 are only set when values are revealed, the salted
+// fingerprint in Metadata tells whether a value changed otherwise, which
+// FingerprintMatches reports. Values that are not valid text have encoding set
+// and are base64 encoded.
+type keyOutput struct {
+	Key                string                `json:"key"`
+	Value              *string               `json:"value,omitempty"`
+	Encoding           string                `json:"encoding,omitempty"`
+	Length             int                   `json:"length"`
+	SHA256             string                `json:"sha256,omitempty"`
+	FingerprintMatches *bool                 `json:"fingerprintMatches,omitempty"`
+	Metadata           *models.KeyAnnotation `json:"metadata,omitempty"`
+}
+
+// secretListOutput is the schema of list in json and yaml output
+type secretListOutput struct {
+	Items []secretSummaryOutput `json:"items"`
+}
+
+// secretSummaryOutput is the schema of a listed Secret, without values
+type secretSummaryOutput struct {
+	Name      string   `json:"name"`
+	Namespace string   `json:"namespace"`
+	Type      string   `json:"type"`
+	Keys      []string `json:"keys"`
+}
+
+// addOutputFlag registers the --output flag listing the supported formats
+func addOutputFlag(cmd *cobra.Command, formats ...string) {
+	cmd.Flags().StringP("output", "o", outputTable, fmt.Sprintf("Output format, one of: %s", strings.Join(formats, ", ")))
+}
+
+// getOutputFormat returns the --output flag value after checking it is supported
+func getOutputFormat(cmd *cobra.Command, formats ...string) (string, error) {
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return "", err
+	}
+	for _, supported := range formats {
+		if format == supported {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported output format %q, must be one of: %s", format, strings.Join(formats, ", "))
+}
+
+func newSecretOutput(secret *v1.Secret, reveal bool) (*secretOutput, error) {
+	out := &secretOutput{
+		Name:      secret.Name,
+		Namespace: secret.Namespace,
+		Type:      string(secret.Type),
+		Keys:      []keyOutput{},
+	}
+
+	for _, key := range sortedKeys(secret.Data) {
+		annotation, err := models.GetKeyAnnotation(secret, key)
+		if err != nil {
+			return nil, err
+		}
+
+		value := secret.Data[key]
+		item := keyOutput{
+			Key:      key,
+			Length:   len(value),
+			Metadata: annotation,
+		}
+
+		if annotation != nil && annotation.Fingerprint != "" {
+			matches := annotation.MatchesValue(value)
+			item.FingerprintMatches = &matches
+		}
+
+		text := string(value)
+		if dotenv.IsBinary(value) {
+			text = base64.StdEncoding.EncodeToString(value)
+			item.Encoding = encodingBase64
+		}
+		if reveal {
+			item.Value = &text
+			item.SHA256 = models.Fingerprint(value)
+		}
+		out.Keys = append(out.Keys, item)
+	}
+	return out, nil
+}
+
+func newSecretSummaryOutput(secret *v1.Secret) secretSummaryOutput {
+	return secretSummaryOutput{
+		Name:      secret.Name,
+		Namespace: secret.Namespace,
+		Type:      string(secret.Type),
+		Keys:      sortedKeys(secret.Data),
+	}
+}
+
+// printStructured writes v as json or yaml
+func printStructured(format string, v interface{}) error {
+	var out []byte
+	var err error
+
+	if format == outputYAML {
+		out, err = yaml.Marshal(v)
+	} else {
+		out, err = json.MarshalIndent(v, "", "  ")
+		out = append(out, '\n')
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// printEnv writes data in the same format pull uses for .env files
+func printEnv(data map[string][]byte) error {
+	out, err := dotenv.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// printShell writes data as export statements that can be evaluated by a
+// POSIX shell. Keys that cannot be shell variables are skipped with a warning.
+func printShell(data map[string][]byte) {
+	for _, key := range sortedKeys(data) {
+		value := data[key]
+		if !shellNamePattern.MatchString(key) {
+			fmt.Fprintf(os.Stderr, "Warning: skipping key %s, not a valid shell variable name\n", key)
+			continue
+		}
+		if strings.ContainsRune(string(value), 0) {
+			fmt.Fprintf(os.Stderr, "Warning: skipping key %s, value contains a NUL byte\n", key)
+			continue
+		}
+		fmt.Printf("export %s=%s\n", key, shellQuote(string(value)))
+	}
+}
+
+// maskValue describes a value without revealing it: its length, whether it
+// is text or binary and a prefix of its SHA-256 digest
+func maskValue(value []byte) string {
+	kind := "text"
+	if dotenv.IsBinary(value) {
+		kind = "binary"
+	}
+	return fmt.Sprintf("<%d bytes, %s, sha256:%s>", len(value), kind, models.Fingerprint(value)[:8])
+}
+
+// shellQuote wraps a value in single quotes, which the shell takes literally
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+func sortedKeys(data map[string][]byte) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestShellQuote(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, `'plain'`, shellQuote("plain"))
+	assert.Equal(t, `''`, shellQuote(""))
+	assert.Equal(t, `'it'\''s $HOME'`, shellQuote("it's $HOME"))
+	assert.Equal(t, "'multi\nline'", shellQuote("multi\nline"))
+}
+
+func TestNewSecretOutput(t *testing.T) {
+	t.Parallel()
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"ksec.io/text": `{"updatedBy":"testuser","lastUpdated":"2023-01-01T00:00:00Z"}`,
+			},
+		},
+		Type: v1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"text":   []byte("value"),
+			"binary": {0x00, 0xff},
+		},
+	}
+
+	metadata := &models.KeyAnnotation{UpdatedBy: "testuser", LastUpdated: "2023-01-01T00:00:00Z"}
+	binaryHash := models.Fingerprint([]byte{0x00, 0xff})
+	textHash := models.Fingerprint([]byte("value"))
+
+	out, err := newSecretOutput(secret, false)
+	assert.NoError(t, err)
+	assert.Equal(t, &secretOutput{
+		Name:      "test",
+		Namespace: "default",
+		Type:      "Opaque",
+		Keys: []keyOutput{
+			{Key: "binary", Encoding: encodingBase64, Length: 2},
+			{Key: "text", Length: 5, Metadata: metadata},
+		},
+	}, out, "The unsalted digest should only be included with revealed values")
+
+	binaryValue, textValue := "AP8=", "value"
+	out, err = newSecretOutput(secret, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []keyOutput{
+		{Key: "binary", Value: &binaryValue, Encoding: encodingBase64, Length: 2, SHA256: binaryHash},
+		{Key: "text", Value: &textValue, Length: 5, SHA256: textHash, Metadata: metadata},
+	}, out.Keys)
+}
+
+func TestNewSecretOutputFingerprint(t *testing.T) {
+	t.Parallel()
+
+	annotation := &models.KeyAnnotation{UpdatedBy: "testuser", LastUpdated: "2023-01-01T00:00:00Z"}
+	assert.NoError(t, annotation.SetFingerprint([]byte("value")))
+	raw, err := json.Marshal(annotation)
+	assert.NoError(t, err)
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"ksec.io/same":    string(raw),
+				"ksec.io/changed": string(raw),
+			},
+		},
+		Data: map[string][]byte{
+			"same":    []byte("value"),
+			"changed": []byte("other"),
+		},
+	}
+
+	out, err := newSecretOutput(secret, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", out.Keys[0].Key)
+	assert.False(t, *out.Keys[0].FingerprintMatches, "A value changed without ksec should not match its fingerprint")
+	assert.True(t, *out.Keys[1].FingerprintMatches)
+}
+
+func TestMaskValue(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "<5 bytes, text, sha256:cd42404d>", maskValue([]byte("value")))
+	assert.Equal(t, "<2 bytes, binary, sha256:", maskValue([]byte{0x00, 0xff})[:25])
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	dryRunNone   = "none"
+	dryRunClient = "client"
+	dryRunServer = "server"
+)
+
+// addPlanFlags registers the flags shared by commands that modify Secret keys
+func addPlanFlags(cmd *cobra.Command) {
+	cmd.Flags().String("dry-run", dryRunNone, `Must be "none", "client" or "server". "client" only prints the planned changes, "server" sends them to the API server for validation without persisting them`)
+	cmd.Flags().Lookup("dry-run").NoOptDefVal = dryRunClient
+	cmd.Flags().Bool("show-values", false, "Show values in the planned changes (Default: masked)")
+	cmd.Flags().BoolP("yes", "y", false, "do not ask for confirmation")
+}
+
+// planOptions holds the flags registered by addPlanFlags
+type planOptions struct {
+	dryRun     string
+	showValues bool
+	yes        bool
+}
+
+func getPlanOptions(cmd *cobra.Command) (*planOptions, error) {
+	dryRun, err := cmd.Flags().GetString("dry-run")
+	if err != nil {
+		return nil, err
+	}
+	if dryRun != dryRunNone && dryRun != dryRunClient && dryRun != dryRunServer {
+		return nil, fmt.Errorf(`invalid --dry-run value %q, must be "none", "client" or "server"`, dryRun)
+	}
+
+	showValues, err := cmd.Flags().GetBool("show-values")
+	if err != nil {
+		return nil, err
+	}
+	yes, err := cmd.Flags().GetBool("yes")
+	if err != nil {
+		return nil, err
+	}
+
+	return &planOptions{dryRun: dryRun, showValues: showValues, yes: yes}, nil
+}
+
+// plan is the set of changes a command is about to make to a Secret
+type plan struct {
+	name    string
+	secret  *v1.Secret
+	desired map[string][]byte
+	changes []models.KeyChange
+}
+
+// newPlan computes the changes of setting data and removing keys from a
+// Secret, which is nil when it does not exist yet
+func newPlan(name string, secret *v1.Secret, data map[string][]byte, remove []string) *plan {
+	var current map[string][]byte
+	if secret != nil {
+		current = secret.Data
+	}
+	desired := models.MergeData(current, data, remove)
+	return &plan{name: name, secret: secret, desired: desired, changes: models.Diff(current, desired)}
+}
+
+// validate checks that the Secret still has the keys its type requires and
+// fits in the size limit of a Secret after the changes
+func (p *plan) validate(secretType v1.SecretType) error {
+	if size := models.DataSize(p.desired); size > models.MaxSecretSize {
+		return fmt.Errorf("secret %s would be %d bytes, larger than the %d byte limit of a Secret", p.name, size, models.MaxSecretSize)
+	}
+	return models.ValidateSecretData(secretType, p.desired)
+}
+
+// getSecretIfExists returns the Secret or nil when it does not exist
+func getSecretIfExists(ctx context.Context, name string) (*v1.Secret, error) {
+	secret, err := secretsClient.Get(ctx, name)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	return secret, err
+}
+
+// confirm prints the plan and returns the client to apply it with. It
+// returns false when nothing should be applied, because of a client dry run,
+// no changes, or a declined confirmation. Interactive sessions are always
+// asked. prompt requires the confirmation, so without a terminal to ask it
+// on an error is returned unless --yes is given.
+func (o *planOptions) confirm(p *plan, prompt bool) (*models.SecretsClient, bool, error) {
+	if len(p.changes) == 0 && p.secret != nil {
+		fmt.Printf("No changes to secret \"%s\"\n", p.name)
+		return nil, false, nil
+	}
+
+	o.print(p)
+
+	if o.dryRun == dryRunClient {
+		fmt.Println("Dry run, no changes applied")
+		return nil, false, nil
+	}
+	if o.dryRun == dryRunServer || o.yes {
+		return o.client(), true, nil
+	}
+
+	interactive := isInteractive()
+	if prompt && !interactive {
+		return nil, false, fmt.Errorf("the changes to secret %s need a confirmation and stdin is not a terminal, use --yes to apply them", p.name)
+	}
+	if interactive && !askConfirmation("Apply these changes?") {
+		fmt.Println("Changes canceled")
+		return nil, false, nil
+	}
+	return o.client(), true, nil
+}
+
+// client returns the client to write with, which only validates the writes
+// on a server dry run
+func (o *planOptions) client() *models.SecretsClient {
+	if o.dryRun == dryRunServer {
+		return secretsClient.WithDryRun()
+	}
+	return secretsClient
+}
+
+// done reports the outcome of applying a plan
+func (o *planOptions) done(p *plan) {
+	if o.dryRun == dryRunServer {
+		fmt.Printf("Changes to secret \"%s\" validated by the server, nothing persisted (dry run)\n", p.name)
+	}
+}
+
+func (o *planOptions) print(p *plan) {
+	if p.secret == nil {
+		fmt.Printf("Create secret \"%s\":\n", p.name)
+	} else {
+		fmt.Printf("Update secret \"%s\":\n", p.name)
+	}
+
+	for _, change := range p.changes {
+		switch change.Type {
+		case models.ChangeAdded:
+			fmt.Printf("  + %s: %s\n", change.Key, o.value(change.New))
+		case models.ChangeChanged:
+			fmt.Printf("  ~ %s: %s => %s\n", change.Key, o.value(change.Old), o.value(change.New))
+		case models.ChangeRemoved:
+			fmt.Printf("  - %s\n", change.Key)
+		}
+	}
+}
+
+func (o *planOptions) value(value []byte) string {
+	if !o.showValues {
+		return maskValue(value)
+	}
+	return fmt.Sprintf("%q", value)
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 
+	"github.com/kanopy-platform/ksec/pkg/dotenv"
 	"github.com/spf13/cobra"
 )
 
@@ -17,26 +19,72 @@ var pullCmd = &cobra.Command{
 
 func pullCommand(cmd *cobra.Command, args []string) error {
 	name := args[0]
+	path := args[1]
 	ctx := context.Background()
 
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return err
+	}
+	merge, err := cmd.Flags().GetBool("merge")
+	if err != nil {
+		return err
+	}
+	commentMissing, err := cmd.Flags().GetBool("comment-missing")
+	if err != nil {
+		return err
+	}
+
+	if commentMissing && !merge {
+		return fmt.Errorf("--comment-missing can only be used with --merge")
+	}
+
+	exists := fileExists(path)
+	if exists && !force && !merge {
+		return fmt.Errorf("file %s already exists, use --force to overwrite it or --merge to update it", path)
+	}
+
 	secret, err := secretsClient.Get(ctx, name)
 	if err != nil {
 		return err
 	}
 
-	file, err := os.Create(args[1])
+	var content []byte
+	if merge && exists {
+		content, err = mergeSecretData(path, secret.Data, commentMissing)
+	} else {
+		content, err = dotenv.Marshal(secret.Data)
+	}
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	for key, value := range secret.Data {
-		_, err = file.WriteString(fmt.Sprintf("%s=%s\n", key, value))
-		if err != nil {
-			return err
-		}
+	if err := writeSecretFile(path, content); err != nil {
+		return err
 	}
 
-	file.Sync()
+	warnIfNotGitIgnored(path)
 	return nil
 }
+
+// mergeSecretData updates the .env file at path with data, keeping its comments and ordering
+func mergeSecretData(path string, data map[string][]byte, commentMissing bool) ([]byte, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := dotenv.ParseDocument(bytes.NewReader(existing), false)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := doc.Merge(data, commentMissing)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Merged into \"%s\": %d updated, %d added, %d commented out\n",
+		path, len(result.Updated), len(result.Added), len(result.Commented))
+	return doc.Bytes(), nil
+}
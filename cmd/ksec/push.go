@@ -1,12 +1,14 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"fmt"
 	"io"
 	"os"
-	"strings"
+	"path/filepath"
 
+	"github.com/kanopy-platform/ksec/pkg/dotenv"
+	"github.com/kanopy-platform/ksec/pkg/models"
 	"github.com/spf13/cobra"
 )
 
@@ -22,39 +24,72 @@ func pushCommand(cmd *cobra.Command, args []string) error {
 	secretName := args[1]
 	ctx := context.Background()
 
+	opts, err := getPlanOptions(cmd)
+	if err != nil {
+		return err
+	}
+	strict, err := cmd.Flags().GetBool("strict")
+	if err != nil {
+		return err
+	}
+	prune, err := cmd.Flags().GetBool("prune")
+	if err != nil {
+		return err
+	}
+
 	file, err := os.Open(fileArg)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	data, err := readSecretData(file)
+	data, err := readSecretData(file, strict)
 	if err != nil {
-		return err
+		return fmt.Errorf("%s: %w", fileArg, err)
 	}
 
-	_, err = secretsClient.Upsert(ctx, secretName, data)
+	secret, err := getSecretIfExists(ctx, secretName)
 	if err != nil {
 		return err
 	}
-	return nil
-}
 
-func readSecretData(reader io.Reader) (map[string][]byte, error) {
-	data := map[string][]byte{}
-	scanner := bufio.NewScanner(reader)
+	var stale []string
+	if prune && secret != nil {
+		stale = models.StaleKeys(secret, data)
+	}
 
-	for scanner.Scan() {
-		split := strings.SplitN(scanner.Text(), "=", 2)
+	secretType, err := getSecretType(cmd, secret)
+	if err != nil {
+		return err
+	}
 
-		if len(split) > 1 {
-			data[split[0]] = []byte(split[1])
-		}
+	p := newPlan(secretName, secret, data, stale)
+	if err := p.validate(secretType); err != nil {
+		return err
+	}
+	// removing keys always needs a confirmation, even when not interactive
+	client, ok, err := opts.confirm(p, len(stale) > 0)
+	if !ok {
+		return err
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	ctx = models.WithChangeInfo(ctx, models.ChangeInfo{Operation: models.OperationPush, Source: filepath.Base(fileArg)})
+	if secret == nil {
+		_, err = client.CreateWithType(ctx, secretName, secretType, data)
+	} else {
+		_, err = client.Apply(ctx, secret, models.Mutation{Set: data, Remove: stale})
 	}
+	if err != nil {
+		return err
+	}
+
+	opts.done(p)
+	return nil
+}
 
-	return data, nil
+func readSecretData(reader io.Reader, strict bool) (map[string][]byte, error) {
+	if strict {
+		return dotenv.ParseStrict(reader)
+	}
+	return dotenv.Parse(reader)
 }
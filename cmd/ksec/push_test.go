@@ -17,7 +17,7 @@ key2=value2
 key3
 `)
 
-	data, err := readSecretData(reader)
+	data, err := readSecretData(reader, false)
 	assert.NoError(t, err)
 
 	assert.Equal(t, "value", string(data["key"]))
@@ -27,3 +27,15 @@ key3
 	_, ok := data["key3"]
 	assert.False(t, ok)
 }
+
+func TestScanSecretDataStrict(t *testing.T) {
+	t.Parallel()
+
+	reader := strings.NewReader(`
+key=value
+key3
+`)
+
+	_, err := readSecretData(reader, true)
+	assert.EqualError(t, err, `line 3: expected KEY=VALUE, got "key3"`)
+}
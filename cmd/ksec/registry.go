@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+)
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Manage registry credentials in kubernetes.io/dockerconfigjson Secrets",
+}
+
+var registryAddCmd = &cobra.Command{
+	Use:   "add [secret]",
+	Short: "Add or replace the credentials of a registry",
+	Example: `  ksec registry add regcred --registry ghcr.io --username bot --password-stdin < token.txt
+  ksec registry add regcred --registry ghcr.io --username bot --password-stdin --attach-to serviceaccount/default`,
+	Args: cobra.ExactArgs(1),
+	RunE: registryAddCommand,
+}
+
+var registryRemoveCmd = &cobra.Command{
+	Use:   "remove [secret]",
+	Short: "Remove the credentials of a registry",
+	Args:  cobra.ExactArgs(1),
+	RunE:  registryRemoveCommand,
+}
+
+var registryListCmd = &cobra.Command{
+	Use:   "list [secret]",
+	Short: "List the registries of a Secret",
+	Args:  cobra.ExactArgs(1),
+	RunE:  registryListCommand,
+}
+
+var registryListOutputFormats = []string{outputTable, outputJSON, outputYAML, outputName}
+
+// registryOutput is the schema of a registry in json and yaml output,
+// passwords are never printed
+type registryOutput struct {
+	Server   string `json:"server"`
+	Username string `json:"username,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+func registryAddCommand(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	ctx := context.Background()
+
+	opts, err := getPlanOptions(cmd)
+	if err != nil {
+		return err
+	}
+	server, err := cmd.Flags().GetString("registry")
+	if err != nil {
+		return err
+	}
+	username, err := cmd.Flags().GetString("username")
+	if err != nil {
+		return err
+	}
+	email, err := cmd.Flags().GetString("email")
+	if err != nil {
+		return err
+	}
+	password, err := cmd.Flags().GetString("password")
+	if err != nil {
+		return err
+	}
+	passwordStdin, err := cmd.Flags().GetBool("password-stdin")
+	if err != nil {
+		return err
+	}
+	attachTo, err := cmd.Flags().GetString("attach-to")
+	if err != nil {
+		return err
+	}
+
+	var serviceAccount string
+	if attachTo != "" {
+		serviceAccount, err = parseServiceAccountRef(attachTo)
+		if err != nil {
+			return err
+		}
+	}
+
+	if passwordStdin {
+		raw, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+		password = strings.TrimRight(string(raw), "\r\n")
+	}
+	if password == "" {
+		return fmt.Errorf("a password is required, use --password-stdin or --password")
+	}
+
+	secret, config, err := getDockerConfigSecret(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := config.Set(server, models.NewDockerConfigEntry(username, password, email)); err != nil {
+		return err
+	}
+
+	p, ok, err := applyDockerConfig(ctx, opts, name, secret, config, server)
+	if err != nil {
+		return err
+	}
+
+	// the Secret is attached even when it was already up to date
+	unchanged := len(p.changes) == 0 && opts.dryRun != dryRunClient
+	if serviceAccount != "" && (ok || unchanged) {
+		attached, err := opts.client().AttachImagePullSecret(ctx, serviceAccount, name)
+		if err != nil {
+			return err
+		}
+		switch {
+		case attached && opts.dryRun == dryRunServer:
+			fmt.Printf("Adding secret \"%s\" to the imagePullSecrets of service account \"%s\" validated by the server, nothing persisted (dry run)\n", name, serviceAccount)
+		case attached:
+			fmt.Printf("Added secret \"%s\" to the imagePullSecrets of service account \"%s\"\n", name, serviceAccount)
+		}
+	}
+	return nil
+}
+
+func registryRemoveCommand(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	ctx := context.Background()
+
+	opts, err := getPlanOptions(cmd)
+	if err != nil {
+		return err
+	}
+	server, err := cmd.Flags().GetString("registry")
+	if err != nil {
+		return err
+	}
+
+	secret, config, err := getDockerConfigSecret(ctx, name)
+	if err != nil {
+		return err
+	}
+	if secret == nil || !config.Remove(server) {
+		return fmt.Errorf("secret %s has no credentials for registry %s", name, server)
+	}
+
+	_, _, err = applyDockerConfig(ctx, opts, name, secret, config, server)
+	return err
+}
+
+func registryListCommand(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	format, err := getOutputFormat(cmd, registryListOutputFormats...)
+	if err != nil {
+		return err
+	}
+
+	secret, err := secretsClient.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if secret.Type != v1.SecretTypeDockerConfigJson {
+		return fmt.Errorf("secret %s has type %s, not %s", secret.Name, secret.Type, v1.SecretTypeDockerConfigJson)
+	}
+	config, err := models.ParseDockerConfig(secret.Data[v1.DockerConfigJsonKey])
+	if err != nil {
+		return err
+	}
+
+	registries := []registryOutput{}
+	for _, server := range config.Servers() {
+		entry, _, err := config.Entry(server)
+		if err != nil {
+			return err
+		}
+		registries = append(registries, registryOutput{Server: server, Username: entry.Username, Email: entry.Email})
+	}
+
+	switch format {
+	case outputJSON, outputYAML:
+		return printStructured(format, registries)
+	case outputName:
+		for _, registry := range registries {
+			fmt.Println(registry.Server)
+		}
+		return nil
+	}
+
+	lines := []string{"SERVER\tUSERNAME"}
+	for _, registry := range registries {
+		lines = append(lines, fmt.Sprintf("%s\t%s", registry.Server, registry.Username))
+	}
+	outputTabular(lines)
+	return nil
+}
+
+// getDockerConfigSecret returns the Secret and its registries, the Secret is
+// nil when it does not exist yet
+func getDockerConfigSecret(ctx context.Context, name string) (*v1.Secret, *models.DockerConfig, error) {
+	secret, err := getSecretIfExists(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if secret == nil {
+		config, err := models.ParseDockerConfig(nil)
+		return nil, config, err
+	}
+
+	if secret.Type != v1.SecretTypeDockerConfigJson {
+		return nil, nil, fmt.Errorf("secret %s has type %s, not %s", name, secret.Type, v1.SecretTypeDockerConfigJson)
+	}
+	config, err := models.ParseDockerConfig(secret.Data[v1.DockerConfigJsonKey])
+	return secret, config, err
+}
+
+// applyDockerConfig writes config to the Secret after confirming the plan. It
+// returns false when nothing was written.
+func applyDockerConfig(ctx context.Context, opts *planOptions, name string, secret *v1.Secret, config *models.DockerConfig, server string) (*plan, bool, error) {
+	raw, err := config.Marshal()
+	if err != nil {
+		return nil, false, err
+	}
+	data := models.DockerConfigJSONData(raw)
+
+	p := newPlan(name, secret, data, nil)
+	client, ok, err := opts.confirm(p, false)
+	if !ok {
+		return p, false, err
+	}
+
+	ctx = models.WithChangeInfo(ctx, models.ChangeInfo{Operation: models.OperationRegistry, Source: server})
+	if secret == nil {
+		_, err = client.CreateWithType(ctx, name, v1.SecretTypeDockerConfigJson, data)
+	} else {
+		_, err = client.Apply(ctx, secret, models.Mutation{Set: data})
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	opts.done(p)
+	return p, true, nil
+}
+
+// parseServiceAccountRef accepts serviceaccount/NAME, sa/NAME or just NAME
+func parseServiceAccountRef(ref string) (string, error) {
+	kind, name, ok := strings.Cut(ref, "/")
+	if !ok {
+		return ref, nil
+	}
+	switch strings.ToLower(kind) {
+	case "serviceaccount", "serviceaccounts", "sa":
+		if name != "" {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("invalid service account %q, must be serviceaccount/NAME", ref)
+}
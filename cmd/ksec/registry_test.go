@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	defer rootCmd.SetIn(nil)
+
+	rootCmd.SetIn(strings.NewReader("s3cret\n"))
+	err := cmdExec([]string{"registry", "add", "regcred", "--registry", "ghcr.io", "-u", "bot", "--password-stdin"})
+	assert.NoError(t, err, "Adding a registry should not return an error")
+
+	err = cmdExec([]string{"registry", "add", "regcred", "--registry", "quay.io", "-u", "robot", "-p", "token"})
+	assert.NoError(t, err, "Adding a second registry should not return an error")
+
+	secret, err := secretsClient.Get(ctx, "regcred")
+	assert.NoError(t, err)
+	assert.Equal(t, v1.SecretTypeDockerConfigJson, secret.Type)
+
+	config, err := models.ParseDockerConfig(secret.Data[v1.DockerConfigJsonKey])
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ghcr.io", "quay.io"}, config.Servers())
+	entry, _, err := config.Entry("ghcr.io")
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", entry.Password, "The trailing newline from stdin should be removed")
+
+	for _, format := range registryListOutputFormats {
+		err = cmdExec([]string{"registry", "list", "regcred", "-o", format})
+		assert.NoError(t, err, "Listing registries as %s should not return an error", format)
+	}
+
+	err = cmdExec([]string{"registry", "remove", "regcred", "--registry", "ghcr.io"})
+	assert.NoError(t, err, "Removing a registry should not return an error")
+	err = cmdExec([]string{"registry", "remove", "regcred", "--registry", "ghcr.io"})
+	assert.EqualError(t, err, "secret regcred has no credentials for registry ghcr.io")
+
+	err = cmdExec([]string{"registry", "add", "regcred", "--registry", "quay.io", "-u", "robot", "-p", "token", "--attach-to", "serviceaccount/missing"})
+	assert.Equal(t, exitCodeNotFound, exitCode(err), "Attaching to a missing service account should fail with NotFound")
+
+	err = cmdExec([]string{"registry", "add", "regcred", "--registry", "quay.io", "-u", "robot"})
+	assert.Error(t, err, "A missing password should return an error")
+}
+
+func TestRegistryKeepsGlobalFlags(t *testing.T) {
+	for _, cmd := range []*cobra.Command{registryAddCmd, registryRemoveCmd} {
+		assert.NotNil(t, cmd.InheritedFlags().Lookup("server"), "The API server flag should not be shadowed by %s", cmd.Name())
+	}
+}
+
+func TestParseServiceAccountRef(t *testing.T) {
+	t.Parallel()
+
+	for _, ref := range []string{"default", "serviceaccount/default", "sa/default", "ServiceAccounts/default"} {
+		name, err := parseServiceAccountRef(ref)
+		assert.NoError(t, err, ref)
+		assert.Equal(t, "default", name, ref)
+	}
+
+	_, err := parseServiceAccountRef("deployment/default")
+	assert.Error(t, err)
+}
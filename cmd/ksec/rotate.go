@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/kanopy-platform/ksec/pkg/generate"
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+)
+
+var rotateCmd = &cobra.Command{
+	Use:   "rotate [secret] [key]",
+	Short: "Rotate the value of a key, keeping the previous value until consumers have switched",
+	Long: `Rotate the value of a key. The current value is moved to <KEY>_PREVIOUS and
+the key is set to the new value in a single update of the Secret, so consumers
+that still use the old value keep working. Once they have switched, --finish
+removes the previous value.
+
+The new value is generated with --generator, read from a file or stdin with
+--value-from, or generated again with the generator recorded in the key
+annotation when neither is given.`,
+	Example: `  ksec rotate api-creds TOKEN --value-from new-token.txt
+  ksec rotate db-creds DB_PASS --generator password:32
+  ksec rotate db-creds DB_PASS --finish`,
+	Args: cobra.ExactArgs(2),
+	RunE: rotateCommand,
+}
+
+func rotateCommand(cmd *cobra.Command, args []string) error {
+	name, key := args[0], args[1]
+	ctx := context.Background()
+
+	opts, err := getPlanOptions(cmd)
+	if err != nil {
+		return err
+	}
+	suffix, err := cmd.Flags().GetString("suffix")
+	if err != nil {
+		return err
+	}
+	finish, err := cmd.Flags().GetBool("finish")
+	if err != nil {
+		return err
+	}
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return err
+	}
+	if suffix == "" {
+		return fmt.Errorf("--suffix must not be empty")
+	}
+	previousKey := key + suffix
+
+	secret, err := secretsClient.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if _, ok := secret.Data[key]; !ok {
+		return fmt.Errorf("key %s does not exist in secret %s", key, name)
+	}
+
+	if finish {
+		if cmd.Flags().Changed("generator") || cmd.Flags().Changed("value-from") {
+			return fmt.Errorf("--finish does not take a new value")
+		}
+		if _, ok := secret.Data[previousKey]; !ok {
+			return fmt.Errorf("key %s has no rotation in progress, %s does not exist in secret %s", key, previousKey, name)
+		}
+		_, err := rotateValues(ctx, opts, secret, key, newKeyValues(), []string{previousKey})
+		return err
+	}
+
+	if _, ok := secret.Data[previousKey]; ok && !force {
+		return fmt.Errorf("a rotation of key %s is in progress, finish it with --finish first or use --force to replace %s", key, previousKey)
+	}
+
+	values, err := rotatedValue(cmd, secret, key)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(values.data[key], secret.Data[key]) {
+		return fmt.Errorf("the new value of key %s is the same as the current one", key)
+	}
+
+	values.data[previousKey] = secret.Data[key]
+	values.sources[previousKey] = key
+	applied, err := rotateValues(ctx, opts, secret, key, values, nil)
+	if err != nil {
+		return err
+	}
+
+	if applied && opts.dryRun == dryRunNone {
+		fmt.Printf("Run \"ksec rotate %s %s --finish\" to remove %s once all consumers use the new value\n", name, key, previousKey)
+	}
+	return nil
+}
+
+// rotatedValue returns the new value of key from --generator or --value-from,
+// or from the generator recorded in the annotation of key
+func rotatedValue(cmd *cobra.Command, secret *v1.Secret, key string) (*keyValues, error) {
+	spec, err := cmd.Flags().GetString("generator")
+	if err != nil {
+		return nil, err
+	}
+	valueFrom, err := cmd.Flags().GetString("value-from")
+	if err != nil {
+		return nil, err
+	}
+
+	values := newKeyValues()
+	switch {
+	case valueFrom == "-":
+		raw, err := readLimited(cmd.InOrStdin(), sourceStdin)
+		if err != nil {
+			return nil, err
+		}
+		values.data[key] = raw
+		values.sources[key] = sourceStdin
+		return values, nil
+	case valueFrom != "":
+		raw, err := readValueFile(valueFrom)
+		if err != nil {
+			return nil, err
+		}
+		values.data[key] = raw
+		values.sources[key] = filepath.Base(valueFrom)
+		return values, nil
+	case spec == "":
+		annotation, err := models.GetKeyAnnotation(secret, key)
+		if err != nil {
+			return nil, err
+		}
+		if annotation == nil || annotation.Generator == "" {
+			return nil, fmt.Errorf("key %s was not generated, use --generator or --value-from to give the new value", key)
+		}
+		spec = annotation.Generator
+	}
+
+	g, err := generate.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return values, values.generate(key, g)
+}
+
+// rotateValues applies values and removes keys in a single update of secret
+// after confirming the plan, recording the rotation of key. It returns false
+// when nothing was written.
+func rotateValues(ctx context.Context, opts *planOptions, secret *v1.Secret, key string, values *keyValues, remove []string) (bool, error) {
+	p := newPlan(secret.Name, secret, values.data, remove)
+	if err := p.validate(secret.Type); err != nil {
+		return false, err
+	}
+	client, ok, err := opts.confirm(p, false)
+	if !ok {
+		return false, err
+	}
+
+	info := values.changeInfo(models.OperationRotate)
+	info.RotatedKeys = map[string]bool{key: true}
+	ctx = models.WithChangeInfo(ctx, info)
+	if _, err := client.Apply(ctx, secret, models.Mutation{Set: values.data, Remove: remove}); err != nil {
+		return false, err
+	}
+
+	opts.done(p)
+	return true, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	defer rootCmd.SetIn(nil)
+
+	err := cmdExec([]string{"set", "rotate-test", "TOKEN=old"})
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "token.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("new"), 0600))
+	err = cmdExec([]string{"rotate", "rotate-test", "TOKEN", "--value-from", path})
+	assert.NoError(t, err, "Rotating a key should not return an error")
+
+	secret, err := secretsClient.Get(ctx, "rotate-test")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("new"), secret.Data["TOKEN"])
+	assert.Equal(t, []byte("old"), secret.Data["TOKEN_PREVIOUS"], "The previous value should be kept")
+
+	annotation, err := models.GetKeyAnnotation(secret, "TOKEN")
+	assert.NoError(t, err)
+	assert.Equal(t, models.OperationRotate, annotation.Operation)
+	assert.Equal(t, "token.txt", annotation.Source)
+	assert.NotEmpty(t, annotation.RotatedAt)
+	assert.Equal(t, annotation.UpdatedBy, annotation.RotatedBy)
+
+	err = cmdExec([]string{"rotate", "rotate-test", "TOKEN", "--value-from", path})
+	assert.ErrorContains(t, err, "a rotation of key TOKEN is in progress")
+
+	err = cmdExec([]string{"rotate", "rotate-test", "TOKEN", "--finish"})
+	assert.NoError(t, err, "Finishing a rotation should not return an error")
+	secret, err = secretsClient.Get(ctx, "rotate-test")
+	assert.NoError(t, err)
+	assert.NotContains(t, secret.Data, "TOKEN_PREVIOUS")
+	assert.NotContains(t, secret.Annotations, "ksec.io/TOKEN_PREVIOUS", "The annotation of the previous value should be removed")
+
+	err = cmdExec([]string{"rotate", "rotate-test", "TOKEN", "--finish"})
+	assert.ErrorContains(t, err, "key TOKEN has no rotation in progress")
+
+	err = cmdExec([]string{"rotate", "rotate-test", "TOKEN"})
+	assert.EqualError(t, err, "key TOKEN was not generated, use --generator or --value-from to give the new value")
+
+	rootCmd.SetIn(strings.NewReader("new"))
+	err = cmdExec([]string{"rotate", "rotate-test", "TOKEN", "--value-from", "-"})
+	assert.EqualError(t, err, "the new value of key TOKEN is the same as the current one")
+}
+
+func TestRotateGenerated(t *testing.T) {
+	ctx := context.Background()
+
+	err := cmdExec([]string{"set", "rotate-generated", "DB_PASS=@gen:alnum:16"})
+	assert.NoError(t, err)
+	secret, err := secretsClient.Get(ctx, "rotate-generated")
+	assert.NoError(t, err)
+	old := secret.Data["DB_PASS"]
+
+	err = cmdExec([]string{"rotate", "rotate-generated", "DB_PASS", "--suffix", "_OLD"})
+	assert.NoError(t, err, "Rotating with the recorded generator should not return an error")
+
+	secret, err = secretsClient.Get(ctx, "rotate-generated")
+	assert.NoError(t, err)
+	assert.Equal(t, old, secret.Data["DB_PASS_OLD"])
+	assert.Len(t, secret.Data["DB_PASS"], 16)
+	assert.NotEqual(t, old, secret.Data["DB_PASS"])
+
+	annotation, err := models.GetKeyAnnotation(secret, "DB_PASS")
+	assert.NoError(t, err)
+	assert.Equal(t, "alnum:16", annotation.Generator)
+
+	err = cmdExec([]string{"rotate", "rotate-generated", "DB_PASS", "--suffix", "_OLD", "--force", "--generator", "hex:8"})
+	assert.NoError(t, err, "Starting a new rotation with --force should not return an error")
+
+	secret, err = secretsClient.Get(ctx, "rotate-generated")
+	assert.NoError(t, err)
+	assert.Len(t, secret.Data["DB_PASS"], 16)
+	annotation, err = models.GetKeyAnnotation(secret, "DB_PASS")
+	assert.NoError(t, err)
+	assert.Equal(t, "hex:8", annotation.Generator)
+
+	err = cmdExec([]string{"rotate", "rotate-generated", "MISSING"})
+	assert.EqualError(t, err, "key MISSING does not exist in secret rotate-generated")
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+)
+
+// addTypeFlag registers the --type flag of commands that can create Secrets
+func addTypeFlag(cmd *cobra.Command) {
+	cmd.Flags().String("type", "", fmt.Sprintf("Type of new Secrets, one of: %s (Default: opaque)", strings.Join(models.SecretTypeNames(), ", ")))
+}
+
+// getSecretType returns the --type of a new Secret, or the type of secret when
+// it exists. The type of an existing Secret is never changed, it has to be
+// deleted and created again.
+func getSecretType(cmd *cobra.Command, secret *v1.Secret) (v1.SecretType, error) {
+	name, err := cmd.Flags().GetString("type")
+	if err != nil {
+		return "", err
+	}
+
+	secretType := v1.SecretTypeOpaque
+	if name != "" {
+		secretType, err = models.ParseSecretType(name)
+		if err != nil {
+			return "", err
+		}
+	}
+	if secret == nil {
+		return secretType, nil
+	}
+
+	current := secret.Type
+	if current == "" {
+		current = v1.SecretTypeOpaque
+	}
+	if name != "" && secretType != current {
+		return "", fmt.Errorf("secret %s has type %s, refusing to change it to %s (delete and create it again to change the type)", secret.Name, current, secretType)
+	}
+	return current, nil
+}
@@ -3,35 +3,103 @@ package main
 import (
 	"context"
 	"fmt"
-	"strings"
 
+	"github.com/kanopy-platform/ksec/pkg/models"
 	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
 )
 
 var setCmd = &cobra.Command{
 	Use:   "set [secret] [key=value...]",
 	Short: "Set values in a Secret",
-	Args:  cobra.MinimumNArgs(2),
-	RunE:  setCommand,
+	Long: `Set values in a Secret.
+
+A value of @path is read from a file as is, so binary files and multi-line
+values such as private keys are stored unchanged. A value of - is read from
+stdin, and keys given to --prompt are read from the terminal without echoing
+them, which keeps values out of the shell history and process list. A value of
+@gen:type[:length] is a random value, see ksec generate, which only replaces an
+existing key with --force. Use @@ for a literal value starting with @.`,
+	Example: `  ksec set api-creds TOKEN=- < token.txt
+  ksec set gcp-creds key.json=@service-account.json
+  ksec set db-creds --prompt PASSWORD
+  ksec set db-creds DB_PASS=@gen:password:32`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: setCommand,
 }
 
 func setCommand(cmd *cobra.Command, args []string) error {
 	name := args[0]
 	dataArgs := args[1:]
-	data := make(map[string][]byte)
 	ctx := context.Background()
 
-	for _, item := range dataArgs {
-		split := strings.SplitN(item, "=", 2)
-		if len(split) != 2 {
-			return fmt.Errorf("Data is not formatted correctly: %s", item)
+	opts, err := getPlanOptions(cmd)
+	if err != nil {
+		return err
+	}
+
+	prompt, err := cmd.Flags().GetStringSlice("prompt")
+	if err != nil {
+		return err
+	}
+
+	force, err := cmd.Flags().GetBool("force")
+	if err != nil {
+		return err
+	}
+
+	values, err := parseValueArgs(cmd, dataArgs)
+	if err != nil {
+		return err
+	}
+	if err := values.prompt(prompt); err != nil {
+		return err
+	}
+	if len(values.data) == 0 {
+		return fmt.Errorf("at least one key=value or --prompt key is required")
+	}
+
+	secret, err := getSecretIfExists(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if !force {
+		if err := values.checkOverwrite(secret); err != nil {
+			return err
 		}
-		data[split[0]] = []byte(split[1])
 	}
 
-	_, err := secretsClient.Upsert(ctx, name, data)
+	secretType, err := getSecretType(cmd, secret)
+	if err != nil {
+		return err
+	}
+
+	return setValues(ctx, opts, name, secret, secretType, values, models.OperationSet)
+}
+
+// setValues writes values to a Secret of secretType after confirming the
+// plan, creating the Secret when it is nil
+func setValues(ctx context.Context, opts *planOptions, name string, secret *v1.Secret, secretType v1.SecretType, values *keyValues, operation string) error {
+	p := newPlan(name, secret, values.data, nil)
+	if err := p.validate(secretType); err != nil {
+		return err
+	}
+	client, ok, err := opts.confirm(p, false)
+	if !ok {
+		return err
+	}
+
+	ctx = models.WithChangeInfo(ctx, values.changeInfo(operation))
+	if secret == nil {
+		_, err = client.CreateWithType(ctx, name, secretType, values.data)
+	} else {
+		_, err = client.Apply(ctx, secret, models.Mutation{Set: values.data})
+	}
 	if err != nil {
 		return err
 	}
+
+	opts.done(p)
 	return nil
 }
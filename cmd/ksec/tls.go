@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kanopy-platform/ksec/pkg/certs"
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+	v1 "k8s.io/api/core/v1"
+)
+
+// tlsCAKey is the conventional key of the CA certificate in TLS Secrets
+const tlsCAKey = "ca.crt"
+
+// tlsExpiryWarningDays is how close to expiry a stored certificate triggers a warning
+const tlsExpiryWarningDays = 30
+
+var tlsCmd = &cobra.Command{
+	Use:   "tls",
+	Short: "Manage kubernetes.io/tls Secrets",
+}
+
+var tlsSetCmd = &cobra.Command{
+	Use:   "set [secret]",
+	Short: "Store a certificate chain and private key in a TLS Secret",
+	Long: `Store a certificate chain and private key in a kubernetes.io/tls Secret.
+
+The private key must match the first certificate of the chain, every
+certificate must be issued by the one following it, and none of them may be
+expired. With --ca the chain must also verify against the CA certificate,
+which is stored as ca.crt.`,
+	Example: `  ksec tls set ingress-tls --cert fullchain.pem --key key.pem --ca ca.pem`,
+	Args:    cobra.ExactArgs(1),
+	RunE:    tlsSetCommand,
+}
+
+var tlsInspectCmd = &cobra.Command{
+	Use:   "inspect [secret]",
+	Short: "Show the certificates stored in a Secret",
+	Args:  cobra.ExactArgs(1),
+	RunE:  tlsInspectCommand,
+}
+
+var tlsInspectOutputFormats = []string{outputTable, outputJSON, outputYAML}
+
+// tlsInspectOutput is the schema of tls inspect in json and yaml output
+type tlsInspectOutput struct {
+	Name         string                  `json:"name"`
+	Namespace    string                  `json:"namespace"`
+	Certificates []certificateInfoOutput `json:"certificates"`
+}
+
+// certificateInfoOutput describes a certificate stored in a Secret key, index
+// is its position in the PEM bundle
+type certificateInfoOutput struct {
+	Key   string `json:"key"`
+	Index int    `json:"index"`
+	certs.Info
+}
+
+func tlsSetCommand(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	ctx := context.Background()
+
+	opts, err := getPlanOptions(cmd)
+	if err != nil {
+		return err
+	}
+	certFile, err := cmd.Flags().GetString("cert")
+	if err != nil {
+		return err
+	}
+	keyFile, err := cmd.Flags().GetString("key")
+	if err != nil {
+		return err
+	}
+	caFile, err := cmd.Flags().GetString("ca")
+	if err != nil {
+		return err
+	}
+
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return err
+	}
+
+	chain, err := certs.ParseCertificates(certPEM)
+	if err != nil {
+		return fmt.Errorf("%s: %w", certFile, err)
+	}
+	key, err := certs.ParsePrivateKey(keyPEM)
+	if err != nil {
+		return fmt.Errorf("%s: %w", keyFile, err)
+	}
+
+	data := models.TLSData(certPEM, keyPEM)
+	keySources := map[string]string{v1.TLSCertKey: filepath.Base(certFile), v1.TLSPrivateKeyKey: filepath.Base(keyFile)}
+
+	var caCerts []*x509.Certificate
+	if caFile != "" {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return err
+		}
+		caCerts, err = certs.ParseCertificates(caPEM)
+		if err != nil {
+			return fmt.Errorf("%s: %w", caFile, err)
+		}
+		data[tlsCAKey] = caPEM
+		keySources[tlsCAKey] = filepath.Base(caFile)
+	}
+
+	now := time.Now()
+	if err := certs.Validate(chain, key, caCerts, now); err != nil {
+		return err
+	}
+	if info := certs.Describe(chain[0], now); info.DaysToExpiry < tlsExpiryWarningDays {
+		fmt.Fprintf(os.Stderr, "Warning: certificate %s expires in %d days\n", info.Subject, info.DaysToExpiry)
+	}
+
+	secret, err := getSecretIfExists(ctx, name)
+	if err != nil {
+		return err
+	}
+	if secret != nil && secret.Type != v1.SecretTypeTLS {
+		return fmt.Errorf("secret %s has type %s, not %s", name, secret.Type, v1.SecretTypeTLS)
+	}
+
+	// a CA left from an earlier certificate would not match the new chain
+	var remove []string
+	if secret != nil && caFile == "" {
+		if _, ok := secret.Data[tlsCAKey]; ok {
+			remove = []string{tlsCAKey}
+		}
+	}
+
+	p := newPlan(name, secret, data, remove)
+	client, ok, err := opts.confirm(p, false)
+	if !ok {
+		return err
+	}
+
+	ctx = models.WithChangeInfo(ctx, models.ChangeInfo{Operation: models.OperationTLS, KeySources: keySources})
+	if secret == nil {
+		_, err = client.CreateWithType(ctx, name, v1.SecretTypeTLS, data)
+	} else {
+		_, err = client.Apply(ctx, secret, models.Mutation{Set: data, Remove: remove})
+	}
+	if err != nil {
+		return err
+	}
+
+	opts.done(p)
+	return nil
+}
+
+func tlsInspectCommand(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	format, err := getOutputFormat(cmd, tlsInspectOutputFormats...)
+	if err != nil {
+		return err
+	}
+
+	secret, err := secretsClient.Get(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	out := tlsInspectOutput{Name: secret.Name, Namespace: secret.Namespace, Certificates: []certificateInfoOutput{}}
+	now := time.Now()
+	for _, key := range []string{v1.TLSCertKey, tlsCAKey} {
+		value, ok := secret.Data[key]
+		if !ok {
+			continue
+		}
+		chain, err := certs.ParseCertificates(value)
+		if err != nil {
+			return fmt.Errorf("key %s: %w", key, err)
+		}
+		for i, cert := range chain {
+			out.Certificates = append(out.Certificates, certificateInfoOutput{Key: key, Index: i, Info: certs.Describe(cert, now)})
+		}
+	}
+	if len(out.Certificates) == 0 {
+		return fmt.Errorf("secret %s has no %s or %s key", secret.Name, v1.TLSCertKey, tlsCAKey)
+	}
+
+	if format != outputTable {
+		return printStructured(format, out)
+	}
+
+	var lines []string
+	for _, cert := range out.Certificates {
+		lines = append(lines, fmt.Sprintf("Key:\t%s [%d]", cert.Key, cert.Index))
+		lines = append(lines, fmt.Sprintf("Subject:\t%s", cert.Subject))
+		if len(cert.SANs) > 0 {
+			lines = append(lines, fmt.Sprintf("SANs:\t%s", strings.Join(cert.SANs, ", ")))
+		}
+		lines = append(lines, fmt.Sprintf("Issuer:\t%s", cert.Issuer))
+		lines = append(lines, fmt.Sprintf("Not After:\t%s (%s)\n", cert.NotAfter.Format(time.RFC3339), describeExpiry(cert.DaysToExpiry)))
+	}
+	outputTabular(lines)
+	return nil
+}
+
+func describeExpiry(days int) string {
+	switch {
+	case days < 0:
+		return fmt.Sprintf("expired %d days ago", -days)
+	case days == 1:
+		return "expires in 1 day"
+	default:
+		return fmt.Sprintf("expires in %d days", days)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+// writeTestKeyPair writes a self-signed certificate and its key to dir
+func writeTestKeyPair(t *testing.T, dir, cn string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	assert.NoError(t, err)
+	keyRaw, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile := filepath.Join(dir, cn+".crt")
+	keyFile := filepath.Join(dir, cn+".key")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}), 0600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyRaw}), 0600))
+	return certFile, keyFile
+}
+
+func TestTLS(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	certFile, keyFile := writeTestKeyPair(t, dir, "example.com")
+	otherCertFile, _ := writeTestKeyPair(t, dir, "other.example.com")
+
+	err := cmdExec([]string{"tls", "set", "tlstest", "--cert", otherCertFile, "--key", keyFile})
+	assert.ErrorContains(t, err, "private key does not match certificate CN=other.example.com")
+
+	err = cmdExec([]string{"tls", "set", "tlstest", "--cert", certFile, "--key", keyFile})
+	assert.NoError(t, err, "Setting a valid certificate should not return an error")
+
+	secret, err := secretsClient.Get(ctx, "tlstest")
+	assert.NoError(t, err)
+	assert.Equal(t, v1.SecretTypeTLS, secret.Type)
+	assert.Contains(t, string(secret.Data["tls.crt"]), "BEGIN CERTIFICATE")
+	assert.Contains(t, secret.Annotations["ksec.io/tls.key"], `"source":"example.com.key"`)
+
+	// an existing TLS Secret is updated in place
+	err = cmdExec([]string{"tls", "set", "tlstest", "--cert", certFile, "--key", keyFile, "--ca", certFile})
+	assert.NoError(t, err, "Updating with a CA should not return an error")
+
+	for _, format := range tlsInspectOutputFormats {
+		err = cmdExec([]string{"tls", "inspect", "tlstest", "-o", format})
+		assert.NoError(t, err, "Inspecting as %s should not return an error", format)
+	}
+
+	err = cmdExec([]string{"tls", "set", "tlstest", "--cert", certFile, "--key", keyFile})
+	assert.NoError(t, err, "Updating without a CA should not return an error")
+	secret, err = secretsClient.Get(ctx, "tlstest")
+	assert.NoError(t, err)
+	assert.NotContains(t, secret.Data, "ca.crt", "A CA that was not given again should be removed")
+
+	err = cmdExec([]string{"set", "opaquetest", "key=value"})
+	assert.NoError(t, err)
+	err = cmdExec([]string{"tls", "set", "opaquetest", "--cert", certFile, "--key", keyFile})
+	assert.EqualError(t, err, "secret opaquetest has type Opaque, not kubernetes.io/tls")
+	err = cmdExec([]string{"tls", "inspect", "opaquetest"})
+	assert.Error(t, err, "Inspecting a secret without certificates should return an error")
+}
+
+func TestDescribeExpiry(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "expires in 30 days", describeExpiry(30))
+	assert.Equal(t, "expires in 1 day", describeExpiry(1))
+	assert.Equal(t, "expired 2 days ago", describeExpiry(-2))
+}
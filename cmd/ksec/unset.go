@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/kanopy-platform/ksec/pkg/models"
 	"github.com/spf13/cobra"
 )
 
@@ -19,21 +20,34 @@ func unsetCommand(cmd *cobra.Command, args []string) error {
 	keys := args[1:]
 	ctx := context.Background()
 
+	opts, err := getPlanOptions(cmd)
+	if err != nil {
+		return err
+	}
+
 	secret, err := secretsClient.Get(ctx, name)
 	if err != nil {
 		return err
 	}
 
-	for _, key := range keys {
-		delete(secret.Data, key)
-		delete(secret.Annotations, fmt.Sprintf("ksec.io/%s", key))
-		fmt.Printf("Removed \"%s\" from secret \"%s\"\n", key, name)
+	p := newPlan(name, secret, nil, keys)
+	if err := p.validate(secret.Type); err != nil {
+		return err
+	}
+	client, ok, err := opts.confirm(p, false)
+	if !ok {
+		return err
 	}
 
-	_, err = secretsClient.Update(ctx, secret, secret.Data)
+	_, err = client.Apply(ctx, secret, models.Mutation{Remove: keys})
 	if err != nil {
 		return err
 	}
 
+	for _, change := range p.changes {
+		fmt.Printf("Removed \"%s\" from secret \"%s\"\n", change.Key, name)
+	}
+
+	opts.done(p)
 	return nil
 }
@@ -2,9 +2,13 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 func outputTabular(lines []string) {
@@ -32,3 +36,42 @@ func askConfirmation(message string) bool {
 
 	return false
 }
+
+// isInteractive reports whether stdin is attached to a terminal
+func isInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// exitError is returned by commands that need a specific process exit code
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// exitCode returns the process exit code for an error returned by a command
+func exitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return 1
+}
+
+// commandExitCode returns the process exit code for an error returned while
+// executing cmd. diff exits with 1 on drift, so its other errors, including
+// invalid arguments and a failed client setup, exit with exitCodeDiffError.
+func commandExitCode(cmd *cobra.Command, err error) int {
+	var exitErr *exitError
+	if cmd == diffCmd && !errors.As(err, &exitErr) {
+		return exitCodeDiffError
+	}
+	return exitCode(err)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kanopy-platform/ksec/pkg/generate"
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	v1 "k8s.io/api/core/v1"
+)
+
+// sourceStdin and sourcePrompt are recorded in key annotations for values that
+// were not given on the command line
+const (
+	sourceStdin  = "stdin"
+	sourcePrompt = "prompt"
+)
+
+// readPassword reads a line from the terminal without echoing it
+var readPassword = func(prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("--prompt requires a terminal")
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	defer fmt.Fprintln(os.Stderr)
+	return term.ReadPassword(fd)
+}
+
+// generatorPrefix marks a value that is generated, e.g. @gen:password:32
+const generatorPrefix = "@gen:"
+
+// keyValues are the values of keys given to a command, with where they came
+// from for the key annotations
+type keyValues struct {
+	data       map[string][]byte
+	sources    map[string]string
+	generators map[string]string
+}
+
+func newKeyValues() *keyValues {
+	return &keyValues{
+		data:       make(map[string][]byte),
+		sources:    make(map[string]string),
+		generators: make(map[string]string),
+	}
+}
+
+// changeInfo returns the ChangeInfo recording the sources and generators of
+// the values
+func (v *keyValues) changeInfo(operation string) models.ChangeInfo {
+	return models.ChangeInfo{Operation: operation, Source: "cli", KeySources: v.sources, KeyGenerators: v.generators}
+}
+
+// generate sets key to a new value of g
+func (v *keyValues) generate(key string, g *generate.Generator) error {
+	value, err := g.Generate()
+	if err != nil {
+		return err
+	}
+	v.data[key] = value
+	v.generators[key] = g.String()
+	return nil
+}
+
+// parseValueArgs reads key=value arguments. A value of @path is read from a
+// file, - from stdin and @gen:spec is generated, @@ escapes a literal value
+// starting with @.
+func parseValueArgs(cmd *cobra.Command, args []string) (*keyValues, error) {
+	values := newKeyValues()
+	stdinKey := ""
+
+	for _, item := range args {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("Data is not formatted correctly: %s", item)
+		}
+
+		switch {
+		case value == "-":
+			if stdinKey != "" {
+				return nil, fmt.Errorf("only one key can be read from stdin, %s and %s both use -", stdinKey, key)
+			}
+			stdinKey = key
+			raw, err := readLimited(cmd.InOrStdin(), sourceStdin)
+			if err != nil {
+				return nil, err
+			}
+			values.data[key] = raw
+			values.sources[key] = sourceStdin
+		case strings.HasPrefix(value, "@@"):
+			values.data[key] = []byte(value[1:])
+		case strings.HasPrefix(value, generatorPrefix):
+			g, err := generate.Parse(strings.TrimPrefix(value, generatorPrefix))
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", key, err)
+			}
+			if err := values.generate(key, g); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(value, "@"):
+			path := value[1:]
+			raw, err := readValueFile(path)
+			if err != nil {
+				return nil, err
+			}
+			values.data[key] = raw
+			values.sources[key] = filepath.Base(path)
+		default:
+			values.data[key] = []byte(value)
+		}
+	}
+	return values, nil
+}
+
+// checkOverwrite refuses to replace existing keys of secret with generated
+// values, so running the same command twice does not change them
+func (v *keyValues) checkOverwrite(secret *v1.Secret) error {
+	if secret == nil {
+		return nil
+	}
+	for _, key := range sortedKeys(v.data) {
+		if _, ok := secret.Data[key]; ok && v.generators[key] != "" {
+			return fmt.Errorf("key %s already exists in secret %s, use --force to replace it with a generated value", key, secret.Name)
+		}
+	}
+	return nil
+}
+
+// readValueFile returns the raw content of a file, refusing files larger
+// than a Secret can hold before reading them
+func readValueFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > models.MaxSecretSize {
+		return nil, fmt.Errorf("%s is %d bytes, larger than the %d byte limit of a Secret", path, info.Size(), models.MaxSecretSize)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// the size of pipes and devices is only known after reading them
+	return readLimited(f, path)
+}
+
+// readLimited reads r until EOF, failing once more than a Secret can hold
+// has been read
+func readLimited(r io.Reader, name string) ([]byte, error) {
+	raw, err := io.ReadAll(io.LimitReader(r, models.MaxSecretSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > models.MaxSecretSize {
+		return nil, fmt.Errorf("%s is larger than the %d byte limit of a Secret", name, models.MaxSecretSize)
+	}
+	return raw, nil
+}
+
+// prompt asks for the value of each key twice without echoing it
+func (v *keyValues) prompt(keys []string) error {
+	for _, key := range keys {
+		if _, ok := v.data[key]; ok {
+			return fmt.Errorf("key %s is given both as an argument and with --prompt", key)
+		}
+
+		value, err := readPassword(fmt.Sprintf("Value for %s: ", key))
+		if err != nil {
+			return err
+		}
+		confirmation, err := readPassword(fmt.Sprintf("Confirm value for %s: ", key))
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(value, confirmation) {
+			return fmt.Errorf("the values entered for %s do not match", key)
+		}
+
+		v.data[key] = value
+		v.sources[key] = sourcePrompt
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kanopy-platform/ksec/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetValueSources(t *testing.T) {
+	ctx := context.Background()
+	defer rootCmd.SetIn(nil)
+
+	binary := []byte{0x00, 0xff, '\n', 0x7f}
+	path := filepath.Join(t.TempDir(), "key.bin")
+	assert.NoError(t, os.WriteFile(path, binary, 0600))
+
+	rootCmd.SetIn(strings.NewReader("line 1\nline 2\n"))
+	err := cmdExec([]string{"set", "value-sources", "BIN=@" + path, "STDIN=-", "AT=@@literal"})
+	assert.NoError(t, err, "Setting values from a file and stdin should not return an error")
+
+	secret, err := secretsClient.Get(ctx, "value-sources")
+	assert.NoError(t, err)
+	assert.Equal(t, binary, secret.Data["BIN"], "File values should be stored as is")
+	assert.Equal(t, []byte("line 1\nline 2\n"), secret.Data["STDIN"], "Stdin values should be stored as is")
+	assert.Equal(t, []byte("@literal"), secret.Data["AT"])
+
+	annotation, err := models.GetKeyAnnotation(secret, "BIN")
+	assert.NoError(t, err)
+	assert.Equal(t, "key.bin", annotation.Source)
+	annotation, err = models.GetKeyAnnotation(secret, "AT")
+	assert.NoError(t, err)
+	assert.Equal(t, "cli", annotation.Source)
+
+	err = cmdExec([]string{"set", "value-sources", "A=-", "B=-"})
+	assert.EqualError(t, err, "only one key can be read from stdin, A and B both use -")
+
+	err = cmdExec([]string{"set", "value-sources", "A=@" + filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err, "A missing file should return an error")
+
+	err = cmdExec([]string{"set", "value-sources"})
+	assert.EqualError(t, err, "at least one key=value or --prompt key is required")
+}
+
+func TestSetValueSizeLimit(t *testing.T) {
+	large := filepath.Join(t.TempDir(), "large")
+	assert.NoError(t, os.WriteFile(large, make([]byte, models.MaxSecretSize+1), 0600))
+
+	err := cmdExec([]string{"set", "value-size", "A=@" + large})
+	assert.EqualError(t, err, fmt.Sprintf("%s is %d bytes, larger than the %d byte limit of a Secret", large, models.MaxSecretSize+1, models.MaxSecretSize))
+
+	_, err = readLimited(bytes.NewReader(make([]byte, models.MaxSecretSize+1)), sourceStdin)
+	assert.Error(t, err, "Reading more than a Secret can hold from stdin should fail")
+
+	half := filepath.Join(t.TempDir(), "half")
+	assert.NoError(t, os.WriteFile(half, make([]byte, models.MaxSecretSize/2+1), 0600))
+	err = cmdExec([]string{"set", "value-size", "A=@" + half, "B=@" + half})
+	assert.ErrorContains(t, err, "secret value-size would be", "The total size of the Secret should be checked")
+}
+
+func TestSetPrompt(t *testing.T) {
+	ctx := context.Background()
+
+	answers := []string{}
+	original := readPassword
+	defer func() { readPassword = original }()
+	readPassword = func(prompt string) ([]byte, error) {
+		answer := answers[0]
+		answers = answers[1:]
+		return []byte(answer), nil
+	}
+
+	answers = []string{"s3cret", "s3cret"}
+	err := cmdExec([]string{"set", "value-prompt", "--prompt", "PASSWORD", "USER=admin"})
+	assert.NoError(t, err, "Setting a prompted value should not return an error")
+
+	secret, err := secretsClient.Get(ctx, "value-prompt")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("s3cret"), secret.Data["PASSWORD"])
+	assert.Equal(t, []byte("admin"), secret.Data["USER"])
+
+	annotation, err := models.GetKeyAnnotation(secret, "PASSWORD")
+	assert.NoError(t, err)
+	assert.Equal(t, sourcePrompt, annotation.Source)
+
+	answers = []string{"one", "two"}
+	err = cmdExec([]string{"set", "value-prompt", "--prompt", "PASSWORD"})
+	assert.EqualError(t, err, "the values entered for PASSWORD do not match")
+
+	err = cmdExec([]string{"set", "value-prompt", "--prompt", "USER", "USER=admin"})
+	assert.EqualError(t, err, "key USER is given both as an argument and with --prompt")
+}
@@ -0,0 +1,160 @@
+// Package certs parses and validates the PEM certificates and private keys
+// stored in kubernetes.io/tls Secrets.
+package certs
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ParseCertificates returns the certificates of all CERTIFICATE blocks in
+// data, in the order they appear
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("certificate %d: %w", len(certs), err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return certs, nil
+}
+
+// ParsePrivateKey returns the first PKCS #8, PKCS #1 or SEC 1 private key in data
+func ParsePrivateKey(data []byte) (crypto.Signer, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no PEM encoded private key found")
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+		if strings.HasPrefix(block.Type, "ENCRYPTED") || block.Headers["Proc-Type"] != "" {
+			return nil, errors.New("encrypted private keys are not supported")
+		}
+
+		if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("unsupported private key type %T", key)
+			}
+			return signer, nil
+		}
+		if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+		if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unable to parse %s", block.Type)
+	}
+}
+
+// Validate checks that key belongs to the leaf certificate, the first one in
+// chain, that every certificate is issued by the one following it and that
+// none of them is expired at now. When roots are given the chain must also
+// verify against them.
+func Validate(chain []*x509.Certificate, key crypto.Signer, roots []*x509.Certificate, now time.Time) error {
+	if len(chain) == 0 {
+		return errors.New("no certificate given")
+	}
+	leaf := chain[0]
+
+	public, ok := leaf.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !public.Equal(key.Public()) {
+		return fmt.Errorf("private key does not match certificate %s", leaf.Subject)
+	}
+
+	for i, cert := range chain {
+		if now.After(cert.NotAfter) {
+			return fmt.Errorf("certificate %s expired on %s", cert.Subject, cert.NotAfter.Format(time.RFC3339))
+		}
+		if now.Before(cert.NotBefore) {
+			return fmt.Errorf("certificate %s is not valid before %s", cert.Subject, cert.NotBefore.Format(time.RFC3339))
+		}
+		if i > 0 {
+			if err := chain[i-1].CheckSignatureFrom(cert); err != nil {
+				return fmt.Errorf("certificate %s is not issued by %s, the chain must be ordered from the leaf to the root: %w", chain[i-1].Subject, cert.Subject, err)
+			}
+		}
+	}
+
+	if len(roots) == 0 {
+		return nil
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         x509.NewCertPool(),
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	for _, root := range roots {
+		opts.Roots.AddCert(root)
+	}
+	for _, intermediate := range chain[1:] {
+		opts.Intermediates.AddCert(intermediate)
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		return fmt.Errorf("certificate chain does not verify against the CA: %w", err)
+	}
+	return nil
+}
+
+// Info describes a certificate
+type Info struct {
+	Subject      string    `json:"subject"`
+	SANs         []string  `json:"sans,omitempty"`
+	Issuer       string    `json:"issuer"`
+	NotBefore    time.Time `json:"notBefore"`
+	NotAfter     time.Time `json:"notAfter"`
+	DaysToExpiry int       `json:"daysToExpiry"`
+	IsCA         bool      `json:"isCA"`
+}
+
+// Describe returns the Info of cert, with days to expiry counted from now.
+// Expired certificates have a negative DaysToExpiry.
+func Describe(cert *x509.Certificate, now time.Time) Info {
+	var sans []string
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+
+	return Info{
+		Subject:      cert.Subject.String(),
+		SANs:         sans,
+		Issuer:       cert.Issuer.String(),
+		NotBefore:    cert.NotBefore.UTC(),
+		NotAfter:     cert.NotAfter.UTC(),
+		DaysToExpiry: int(math.Floor(cert.NotAfter.Sub(now).Hours() / 24)),
+		IsCA:         cert.IsCA,
+	}
+}
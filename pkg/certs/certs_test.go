@@ -0,0 +1,134 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// testCertificate issues a certificate for cn valid between notBefore and
+// notAfter, self-signed when parent is nil
+func testCertificate(t *testing.T, cn string, isCA bool, parent *x509.Certificate, parentKey crypto.Signer, notBefore, notAfter time.Time) (*x509.Certificate, crypto.Signer) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if !isCA {
+		template.DNSNames = []string{cn, "www." + cn}
+		template.IPAddresses = []net.IP{net.ParseIP("10.0.0.1")}
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(raw)
+	assert.NoError(t, err)
+	return cert, key
+}
+
+func encodeCertificates(certs ...*x509.Certificate) []byte {
+	var out []byte
+	for _, cert := range certs {
+		out = append(out, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return out
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	cert, _ := testCertificate(t, "example.com", false, nil, nil, now.Add(-time.Hour), now.Add(time.Hour))
+	certs, err := ParseCertificates(append([]byte("leading text\n"), encodeCertificates(cert, cert)...))
+	assert.NoError(t, err)
+	assert.Len(t, certs, 2)
+
+	_, err = ParseCertificates([]byte("not pem"))
+	assert.Error(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	ecRaw, err := x509.MarshalECPrivateKey(ecKey)
+	assert.NoError(t, err)
+	pkcs8Raw, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	assert.NoError(t, err)
+
+	for name, block := range map[string]*pem.Block{
+		"pkcs1": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		"sec1":  {Type: "EC PRIVATE KEY", Bytes: ecRaw},
+		"pkcs8": {Type: "PRIVATE KEY", Bytes: pkcs8Raw},
+	} {
+		_, err := ParsePrivateKey(pem.EncodeToMemory(block))
+		assert.NoError(t, err, name)
+	}
+
+	_, err = ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: pkcs8Raw}))
+	assert.EqualError(t, err, "encrypted private keys are not supported")
+	_, err = ParsePrivateKey(encodeCertificates(cert))
+	assert.EqualError(t, err, "no PEM encoded private key found")
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	root, rootKey := testCertificate(t, "Root CA", true, nil, nil, now.Add(-time.Hour), now.Add(365*24*time.Hour))
+	intermediate, intermediateKey := testCertificate(t, "Intermediate CA", true, root, rootKey, now.Add(-time.Hour), now.Add(365*24*time.Hour))
+	notAfter := now.Add(90 * 24 * time.Hour)
+	leaf, leafKey := testCertificate(t, "example.com", false, intermediate, intermediateKey, now.Add(-time.Hour), notAfter)
+
+	assert.NoError(t, Validate([]*x509.Certificate{leaf, intermediate}, leafKey, nil, now))
+	assert.NoError(t, Validate([]*x509.Certificate{leaf, intermediate}, leafKey, []*x509.Certificate{root}, now))
+
+	err := Validate([]*x509.Certificate{leaf, intermediate}, intermediateKey, nil, now)
+	assert.EqualError(t, err, "private key does not match certificate CN=example.com")
+
+	err = Validate([]*x509.Certificate{leaf, root, intermediate}, leafKey, nil, now)
+	assert.ErrorContains(t, err, "certificate CN=example.com is not issued by CN=Root CA, the chain must be ordered")
+
+	err = Validate([]*x509.Certificate{leaf, intermediate}, leafKey, nil, notAfter.Add(time.Hour))
+	assert.ErrorContains(t, err, "certificate CN=example.com expired on")
+
+	otherRoot, _ := testCertificate(t, "Other CA", true, nil, nil, now.Add(-time.Hour), now.Add(time.Hour))
+	err = Validate([]*x509.Certificate{leaf, intermediate}, leafKey, []*x509.Certificate{otherRoot}, now)
+	assert.ErrorContains(t, err, "certificate chain does not verify against the CA")
+}
+
+func TestDescribe(t *testing.T) {
+	t.Parallel()
+
+	root, rootKey := testCertificate(t, "Root CA", true, nil, nil, now.Add(-time.Hour), now.Add(365*24*time.Hour))
+	leaf, _ := testCertificate(t, "example.com", false, root, rootKey, now.Add(-time.Hour), now.Add(10*24*time.Hour+time.Hour))
+
+	info := Describe(leaf, now)
+	assert.Equal(t, "CN=example.com", info.Subject)
+	assert.Equal(t, "CN=Root CA", info.Issuer)
+	assert.Equal(t, []string{"example.com", "www.example.com", "10.0.0.1"}, info.SANs)
+	assert.Equal(t, 10, info.DaysToExpiry)
+	assert.False(t, info.IsCA)
+
+	assert.Equal(t, -1, Describe(leaf, now.Add(11*24*time.Hour)).DaysToExpiry)
+}
@@ -0,0 +1,225 @@
+package dotenv
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+)
+
+// MergeMarker is the comment heading the section Merge appends new keys to
+const MergeMarker = "# Added by ksec"
+
+// Document is a dotenv file that keeps comments, blank lines, malformed lines
+// and ordering intact, so that it can be updated and written back with
+// minimal changes.
+type Document struct {
+	nodes []*node
+}
+
+// node is one logical line which can span several physical lines
+type node struct {
+	raw   string
+	entry *Entry
+}
+
+// MergeResult lists the keys touched by Document.Merge
+type MergeResult struct {
+	Updated   []string
+	Added     []string
+	Commented []string
+}
+
+// ParseDocument reads a dotenv file into a Document. When strict is false
+// malformed lines are kept verbatim instead of returning a *ParseError.
+func ParseDocument(r io.Reader, strict bool) (*Document, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{lines: splitLines(string(src))}
+	doc := &Document{}
+
+	for p.pos < len(p.lines) {
+		start := p.pos
+		entry, ok, err := p.next()
+		if err != nil {
+			if strict {
+				return nil, err
+			}
+			// resume on the line following the one that failed
+			p.pos = start + 1
+			doc.nodes = append(doc.nodes, &node{raw: p.lines[start]})
+			continue
+		}
+
+		n := &node{raw: strings.Join(p.lines[start:p.pos], "\n")}
+		if ok {
+			n.entry = &entry
+		}
+		doc.nodes = append(doc.nodes, n)
+	}
+
+	return doc, nil
+}
+
+// Entries returns every assignment in file order
+func (d *Document) Entries() []Entry {
+	var entries []Entry
+	for _, n := range d.nodes {
+		if n.entry != nil {
+			entries = append(entries, *n.entry)
+		}
+	}
+	return entries
+}
+
+// Data returns the key/value pairs of the Document, later assignments of a
+// key override earlier ones
+func (d *Document) Data() map[string][]byte {
+	return toMap(d.Entries())
+}
+
+// Set updates every assignment of key in place, or appends a new one when
+// the key is not present. Assignments already holding value are left as written.
+func (d *Document) Set(key string, value []byte) error {
+	found := false
+	for _, n := range d.nodes {
+		if n.entry == nil || n.entry.Key != key {
+			continue
+		}
+		found = true
+		if bytes.Equal(n.entry.Value, value) {
+			continue
+		}
+
+		raw, err := FormatEntry(key, value)
+		if err != nil {
+			return err
+		}
+		if n.entry.Export {
+			raw = exportPrefix(raw)
+		}
+		n.raw = strings.TrimSuffix(raw, "\n")
+		n.entry.Value = value
+	}
+
+	if !found {
+		return d.Append(key, value)
+	}
+	return nil
+}
+
+// Append adds an assignment to the end of the Document
+func (d *Document) Append(key string, value []byte) error {
+	raw, err := FormatEntry(key, value)
+	if err != nil {
+		return err
+	}
+	d.nodes = append(d.nodes, &node{
+		raw:   strings.TrimSuffix(raw, "\n"),
+		entry: &Entry{Key: key, Value: value},
+	})
+	return nil
+}
+
+// AppendComment adds a comment line to the end of the Document
+func (d *Document) AppendComment(text string) {
+	d.nodes = append(d.nodes, &node{raw: "# " + text})
+}
+
+// CommentOut turns every assignment of key into a comment
+func (d *Document) CommentOut(key string) {
+	for _, n := range d.nodes {
+		if n.entry == nil || n.entry.Key != key {
+			continue
+		}
+		lines := strings.Split(n.raw, "\n")
+		for i, line := range lines {
+			lines[i] = "# " + line
+		}
+		n.raw = strings.Join(lines, "\n")
+		n.entry = nil
+	}
+}
+
+// Merge updates the Document to hold data. Existing keys are updated in place
+// and new keys are appended in sorted order below MergeMarker. Keys that are
+// not in data are kept unless commentMissing is set.
+func (d *Document) Merge(data map[string][]byte, commentMissing bool) (MergeResult, error) {
+	result := MergeResult{}
+	existing := d.Data()
+
+	if commentMissing {
+		// a key assigned several times is commented out and reported once
+		for key := range existing {
+			if _, ok := data[key]; !ok {
+				d.CommentOut(key)
+				result.Commented = append(result.Commented, key)
+			}
+		}
+		sort.Strings(result.Commented)
+	}
+
+	var added []string
+	for key, value := range data {
+		current, ok := existing[key]
+		if !ok {
+			added = append(added, key)
+			continue
+		}
+		if !bytes.Equal(current, value) {
+			result.Updated = append(result.Updated, key)
+		}
+		if err := d.Set(key, value); err != nil {
+			return result, err
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(result.Updated)
+
+	if len(added) > 0 && !d.hasMarker() {
+		if len(d.nodes) > 0 && strings.TrimSpace(d.nodes[len(d.nodes)-1].raw) != "" {
+			d.nodes = append(d.nodes, &node{})
+		}
+		d.nodes = append(d.nodes, &node{raw: MergeMarker})
+	}
+	for _, key := range added {
+		if err := d.Append(key, data[key]); err != nil {
+			return result, err
+		}
+	}
+	result.Added = added
+
+	return result, nil
+}
+
+func (d *Document) hasMarker() bool {
+	for _, n := range d.nodes {
+		if n.raw == MergeMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// Bytes renders the Document, each line terminated by a newline
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, n := range d.nodes {
+		buf.WriteString(n.raw)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// exportPrefix adds the export prefix to the assignment in a formatted entry,
+// which follows the base64 directive when there is one
+func exportPrefix(raw string) string {
+	if strings.HasPrefix(raw, "#") {
+		directive, assignment, _ := strings.Cut(raw, "\n")
+		return directive + "\nexport " + assignment
+	}
+	return "export " + raw
+}
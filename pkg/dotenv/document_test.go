@@ -0,0 +1,128 @@
+package dotenv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const curated = `# Database settings
+export DB_HOST=localhost   # local override
+DB_PASS='old password'
+
+# Local only
+DEBUG=true
+not a valid line
+PEM="-----BEGIN-----
+abc
+-----END-----"
+`
+
+func TestDocumentPreservesInput(t *testing.T) {
+	t.Parallel()
+
+	doc, err := ParseDocument(strings.NewReader(curated), false)
+	assert.NoError(t, err)
+	assert.Equal(t, curated, string(doc.Bytes()))
+
+	data := doc.Data()
+	assert.Equal(t, "localhost", string(data["DB_HOST"]))
+	assert.Equal(t, "-----BEGIN-----\nabc\n-----END-----", string(data["PEM"]))
+
+	_, err = ParseDocument(strings.NewReader(curated), true)
+	assert.EqualError(t, err, `line 7: expected KEY=VALUE, got "not a valid line"`)
+}
+
+func TestDocumentMerge(t *testing.T) {
+	t.Parallel()
+
+	doc, err := ParseDocument(strings.NewReader(curated), false)
+	assert.NoError(t, err)
+
+	result, err := doc.Merge(map[string][]byte{
+		"DB_HOST": []byte("localhost"),
+		"DB_PASS": []byte("new password"),
+		"PEM":     []byte("-----BEGIN-----\nabc\n-----END-----"),
+		"API_KEY": []byte("abc123"),
+		"BINARY":  {0x00, 0xff},
+	}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, MergeResult{Updated: []string{"DB_PASS"}, Added: []string{"API_KEY", "BINARY"}}, result)
+
+	assert.Equal(t, `# Database settings
+export DB_HOST=localhost   # local override
+DB_PASS='new password'
+
+# Local only
+DEBUG=true
+not a valid line
+PEM="-----BEGIN-----
+abc
+-----END-----"
+
+# Added by ksec
+API_KEY=abc123
+# ksec:base64
+BINARY=AP8=
+`, string(doc.Bytes()))
+
+	// merging again only appends below the existing marker
+	result, err = doc.Merge(map[string][]byte{"DB_HOST": []byte("db.example.com"), "NEW": []byte("1")}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"DB_HOST"}, result.Updated)
+	assert.Equal(t, []string{"NEW"}, result.Added)
+	assert.True(t, strings.HasSuffix(string(doc.Bytes()), "BINARY=AP8=\nNEW=1\n"))
+	assert.Contains(t, string(doc.Bytes()), "\nexport DB_HOST=db.example.com\n")
+}
+
+func TestDocumentMergeCommentMissing(t *testing.T) {
+	t.Parallel()
+
+	doc, err := ParseDocument(strings.NewReader(curated), false)
+	assert.NoError(t, err)
+
+	result, err := doc.Merge(map[string][]byte{"DB_HOST": []byte("localhost")}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"DB_PASS", "DEBUG", "PEM"}, result.Commented)
+
+	assert.Equal(t, `# Database settings
+export DB_HOST=localhost   # local override
+# DB_PASS='old password'
+
+# Local only
+# DEBUG=true
+not a valid line
+# PEM="-----BEGIN-----
+# abc
+# -----END-----"
+`, string(doc.Bytes()))
+
+	assert.Equal(t, map[string][]byte{"DB_HOST": []byte("localhost")}, doc.Data())
+}
+
+func TestDocumentMergeCommentMissingDuplicates(t *testing.T) {
+	t.Parallel()
+
+	doc, err := ParseDocument(strings.NewReader("KEY=1\nOLD=a\nOLD=b\n"), true)
+	assert.NoError(t, err)
+
+	result, err := doc.Merge(map[string][]byte{"KEY": []byte("1")}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"OLD"}, result.Commented, "A key assigned twice should be reported once")
+	assert.Equal(t, "KEY=1\n# OLD=a\n# OLD=b\n", string(doc.Bytes()))
+}
+
+func TestDocumentSetBinaryExport(t *testing.T) {
+	t.Parallel()
+
+	doc, err := ParseDocument(strings.NewReader("export KEY=value\n"), true)
+	assert.NoError(t, err)
+
+	assert.NoError(t, doc.Set("KEY", []byte{0x01}))
+	assert.Equal(t, "# ksec:base64\nexport KEY=AQ==\n", string(doc.Bytes()))
+
+	parsed, err := ParseStrict(strings.NewReader(string(doc.Bytes())))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, parsed["KEY"])
+}
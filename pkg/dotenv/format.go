@@ -0,0 +1,101 @@
+package dotenv
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Base64Directive is written as a comment above values that are not valid
+// text. Parse decodes the value on the following line when it is present.
+const Base64Directive = "ksec:base64"
+
+// barePattern matches values that can be written without quotes
+var barePattern = regexp.MustCompile(`^[-A-Za-z0-9_./:@%+,=?&~]+$`)
+
+// Marshal renders data as a dotenv document with keys in sorted order. Values
+// are quoted and escaped as needed so that Parse returns exactly the same data.
+func Marshal(data map[string][]byte) ([]byte, error) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		line, err := FormatEntry(key, data[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteString(line)
+	}
+	return buf.Bytes(), nil
+}
+
+// FormatEntry renders a single assignment including its trailing newline.
+// Binary values are base64 encoded and preceded by the Base64Directive comment.
+func FormatEntry(key string, value []byte) (string, error) {
+	if !keyPattern.MatchString(key) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+
+	if IsBinary(value) {
+		return fmt.Sprintf("# %s\n%s=%s\n", Base64Directive, key, base64.StdEncoding.EncodeToString(value)), nil
+	}
+	return fmt.Sprintf("%s=%s\n", key, QuoteValue(string(value))), nil
+}
+
+// QuoteValue returns the shortest representation of a text value that parses
+// back to the same string: bare, single quoted, or double quoted with escapes.
+func QuoteValue(value string) string {
+	if value == "" || barePattern.MatchString(value) {
+		return value
+	}
+
+	if !strings.ContainsAny(value, "'\r\n") {
+		return "'" + value + "'"
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// IsBinary reports whether a value cannot be represented as dotenv text,
+// either because it is not valid UTF-8 or holds control characters.
+func IsBinary(value []byte) bool {
+	if !utf8.Valid(value) {
+		return true
+	}
+	for _, r := range string(value) {
+		if r == '\n' || r == '\r' || r == '\t' {
+			continue
+		}
+		if r < 0x20 || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
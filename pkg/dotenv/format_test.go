@@ -0,0 +1,106 @@
+package dotenv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "secret", want: "secret"},
+		{value: "mongodb://host:27017/prod?replicaSet=prod&w=1", want: "mongodb://host:27017/prod?replicaSet=prod&w=1"},
+		{value: "a b", want: "'a b'"},
+		{value: " leading", want: "' leading'"},
+		{value: "#hash", want: "'#hash'"},
+		{value: `say "hi" $USER`, want: `'say "hi" $USER'`},
+		{value: "it's", want: `"it's"`},
+		{value: "line1\nline2", want: `"line1\nline2"`},
+		{value: "back\\slash\r\n\"q\"\t", want: `"back\\slash\r\n\"q\"\t"`},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, QuoteValue(test.value), "value %q", test.value)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+
+	data := map[string][]byte{
+		"b_key":  []byte("two words"),
+		"A_KEY":  []byte("plain"),
+		"binary": {0x00, 0xff, 0x10},
+		"pem":    []byte("-----BEGIN-----\nabc\n-----END-----\n"),
+	}
+
+	out, err := Marshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, `A_KEY=plain
+b_key='two words'
+# ksec:base64
+binary=AP8Q
+pem="-----BEGIN-----\nabc\n-----END-----\n"
+`, string(out))
+
+	parsed, err := ParseStrict(bytes.NewReader(out))
+	assert.NoError(t, err)
+	assert.Equal(t, data, parsed)
+
+	_, err = Marshal(map[string][]byte{"bad key": []byte("x")})
+	assert.Error(t, err)
+}
+
+func TestBase64Directive(t *testing.T) {
+	t.Parallel()
+
+	data, err := ParseStrict(strings.NewReader("#ksec:base64\nA=aGk=\n# ksec:base64\n\nB=aGk=\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hi", string(data["A"]))
+	assert.Equal(t, "aGk=", string(data["B"]), "directive only applies to the line directly below it")
+
+	_, err = ParseStrict(strings.NewReader("# ksec:base64\nA=not base64\n"))
+	assert.EqualError(t, err, `line 2: invalid base64 value for key "A"`)
+}
+
+func TestIsBinary(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, IsBinary([]byte("text\twith\r\nwhitespace and ünïcode")))
+	assert.True(t, IsBinary([]byte{0xff, 0xfe}))
+	assert.True(t, IsBinary([]byte("nul\x00byte")))
+	assert.True(t, IsBinary([]byte("escape\x1b[0m")))
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add("KEY", []byte("value"))
+	f.Add("pem", []byte("-----BEGIN-----\nabc\n-----END-----\n"))
+	f.Add("quotes", []byte(`'single' "double" \back`))
+	f.Add("bin", []byte{0x00, 0xff})
+	f.Add("spaces", []byte("  # not a comment  "))
+
+	f.Fuzz(func(t *testing.T, key string, value []byte) {
+		if !keyPattern.MatchString(key) {
+			t.Skip()
+		}
+
+		out, err := Marshal(map[string][]byte{key: value})
+		assert.NoError(t, err)
+
+		parsed, err := ParseStrict(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("marshaled output %q does not parse: %v", out, err)
+		}
+		if !bytes.Equal(value, parsed[key]) {
+			t.Fatalf("round trip of %q produced %q via %q", value, parsed[key], out)
+		}
+	})
+}
@@ -0,0 +1,248 @@
+// Package dotenv reads and writes .env files in the format understood by
+// docker compose, direnv and python-dotenv.
+package dotenv
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// keyPattern matches the characters Kubernetes allows in Secret data keys
+var keyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// Entry is a single key/value assignment read from a dotenv file
+type Entry struct {
+	Key   string
+	Value []byte
+	Line  int
+
+	// Export is set when the assignment used the "export" prefix
+	Export bool
+}
+
+// ParseError reports a malformed line in a dotenv file
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Parse reads dotenv formatted data and returns its key/value pairs. Malformed
+// lines are skipped, later assignments of a key override earlier ones.
+func Parse(r io.Reader) (map[string][]byte, error) {
+	entries, err := ParseEntries(r, false)
+	if err != nil {
+		return nil, err
+	}
+	return toMap(entries), nil
+}
+
+// ParseStrict is like Parse but returns a *ParseError for the first malformed line
+func ParseStrict(r io.Reader) (map[string][]byte, error) {
+	entries, err := ParseEntries(r, true)
+	if err != nil {
+		return nil, err
+	}
+	return toMap(entries), nil
+}
+
+// ParseEntries returns every assignment in file order. When strict is false
+// malformed lines are skipped instead of returning a *ParseError.
+func ParseEntries(r io.Reader, strict bool) ([]Entry, error) {
+	doc, err := ParseDocument(r, strict)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Entries(), nil
+}
+
+func toMap(entries []Entry) map[string][]byte {
+	data := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		data[entry.Key] = entry.Value
+	}
+	return data
+}
+
+func splitLines(src string) []string {
+	src = strings.TrimPrefix(src, "\ufeff")
+	if src == "" {
+		return nil
+	}
+	lines := strings.Split(src, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+type parser struct {
+	lines []string
+	pos   int
+
+	// base64 is set by an encoding directive and applies to the following line
+	base64 bool
+}
+
+func (p *parser) errorf(line int, format string, args ...interface{}) error {
+	return &ParseError{Line: line + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+// next consumes one logical line, which may span several physical lines when
+// it holds a quoted value. ok is false for blank lines and comments.
+func (p *parser) next() (Entry, bool, error) {
+	lineNo := p.pos
+	line := strings.TrimLeft(p.lines[p.pos], " \t")
+	p.pos++
+
+	encoded := p.base64
+	p.base64 = false
+
+	// the directive and the value it applies to are consumed together
+	if isBase64Directive(line) && p.pos < len(p.lines) {
+		p.base64 = true
+		return p.next()
+	}
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Entry{}, false, nil
+	}
+
+	rest, export := cutExport(line)
+	if export {
+		line = rest
+	}
+
+	idx := strings.IndexByte(line, '=')
+	if idx < 0 {
+		return Entry{}, false, p.errorf(lineNo, "expected KEY=VALUE, got %q", strings.TrimSpace(line))
+	}
+
+	key := strings.TrimSpace(line[:idx])
+	if !keyPattern.MatchString(key) {
+		return Entry{}, false, p.errorf(lineNo, "invalid key %q", key)
+	}
+
+	rest = strings.TrimLeft(line[idx+1:], " \t")
+	var value string
+	var err error
+
+	switch {
+	case strings.HasPrefix(rest, `"`):
+		value, err = p.quoted(lineNo, rest[1:], '"')
+	case strings.HasPrefix(rest, `'`):
+		value, err = p.quoted(lineNo, rest[1:], '\'')
+	default:
+		value = unquoted(rest)
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	if encoded {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return Entry{}, false, p.errorf(lineNo, "invalid base64 value for key %q", key)
+		}
+		return Entry{Key: key, Value: decoded, Line: lineNo + 1, Export: export}, true, nil
+	}
+
+	return Entry{Key: key, Value: []byte(value), Line: lineNo + 1, Export: export}, true, nil
+}
+
+// isBase64Directive reports whether a comment marks the next value as base64 encoded
+func isBase64Directive(line string) bool {
+	if !strings.HasPrefix(line, "#") {
+		return false
+	}
+	return strings.TrimSpace(line[1:]) == Base64Directive
+}
+
+func cutExport(line string) (string, bool) {
+	if !strings.HasPrefix(line, "export") || len(line) == len("export") {
+		return line, false
+	}
+	if c := line[len("export")]; c != ' ' && c != '\t' {
+		return line, false
+	}
+	return strings.TrimLeft(line[len("export"):], " \t"), true
+}
+
+// unquoted strips an inline comment and surrounding whitespace from a bare value
+func unquoted(rest string) string {
+	for i := 1; i < len(rest); i++ {
+		if rest[i] == '#' && (rest[i-1] == ' ' || rest[i-1] == '\t') {
+			rest = rest[:i]
+			break
+		}
+	}
+	return strings.TrimRight(rest, " \t")
+}
+
+// quoted reads a value up to the closing quote, continuing onto following
+// lines when needed. Double quoted values support backslash escapes, single
+// quoted values are taken literally.
+func (p *parser) quoted(lineNo int, rest string, quote byte) (string, error) {
+	var b strings.Builder
+	text := rest
+
+	for {
+		for i := 0; i < len(text); i++ {
+			c := text[i]
+
+			if c == quote {
+				if err := checkTrailing(text[i+1:]); err != nil {
+					return "", p.errorf(p.pos-1, "%s", err)
+				}
+				return b.String(), nil
+			}
+
+			if c == '\\' && quote == '"' && i+1 < len(text) {
+				i++
+				b.WriteString(unescape(text[i]))
+				continue
+			}
+
+			b.WriteByte(c)
+		}
+
+		if p.pos >= len(p.lines) {
+			return "", p.errorf(lineNo, "unterminated %c quoted value", quote)
+		}
+		b.WriteByte('\n')
+		text = p.lines[p.pos]
+		p.pos++
+	}
+}
+
+func unescape(c byte) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 't':
+		return "\t"
+	case '\\', '"', '\'', '$':
+		return string(c)
+	default:
+		return "\\" + string(c)
+	}
+}
+
+func checkTrailing(s string) error {
+	trimmed := strings.TrimLeft(s, " \t")
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return nil
+	}
+	return fmt.Errorf("unexpected %q after closing quote", trimmed)
+}
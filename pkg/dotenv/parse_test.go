@@ -0,0 +1,177 @@
+package dotenv
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]string
+	}{
+		{
+			name:  "simple",
+			input: "key=value\nkey1=\n",
+			want:  map[string]string{"key": "value", "key1": ""},
+		},
+		{
+			name:  "comments and blank lines",
+			input: "# comment\n\n  # indented comment\nkey=value\n",
+			want:  map[string]string{"key": "value"},
+		},
+		{
+			name:  "export prefix",
+			input: "export FOO=bar\nexport\tBAZ=qux\nexported=yes\n",
+			want:  map[string]string{"FOO": "bar", "BAZ": "qux", "exported": "yes"},
+		},
+		{
+			name:  "whitespace around separator",
+			input: "  FOO = bar  \n",
+			want:  map[string]string{"FOO": "bar"},
+		},
+		{
+			name:  "inline comment",
+			input: "FOO=bar # comment\nBAR=a#b\nBAZ=#notacomment\n",
+			want:  map[string]string{"FOO": "bar", "BAR": "a#b", "BAZ": "#notacomment"},
+		},
+		{
+			name:  "double quotes",
+			input: `FOO="a b" # comment` + "\n" + `BAR="with \"escapes\"\n\t\\ \$HOME \q"` + "\n",
+			want:  map[string]string{"FOO": "a b", "BAR": "with \"escapes\"\n\t\\ $HOME \\q"},
+		},
+		{
+			name:  "single quotes are literal",
+			input: `FOO='a \n "b" $c #d'` + "\n",
+			want:  map[string]string{"FOO": `a \n "b" $c #d`},
+		},
+		{
+			name:  "multiline double quoted",
+			input: "KEY=\"-----BEGIN KEY-----\nabc\n-----END KEY-----\"\nNEXT=1\n",
+			want:  map[string]string{"KEY": "-----BEGIN KEY-----\nabc\n-----END KEY-----", "NEXT": "1"},
+		},
+		{
+			name:  "multiline single quoted",
+			input: "KEY='line1\n  line2'\n",
+			want:  map[string]string{"KEY": "line1\n  line2"},
+		},
+		{
+			name:  "crlf line endings",
+			input: "FOO=bar\r\nBAZ=\"qux\"\r\n",
+			want:  map[string]string{"FOO": "bar", "BAZ": "qux"},
+		},
+		{
+			name:  "equals in value",
+			input: "DB_URL=mongodb://host:27017/prod?replicaSet=prod&w=1\n",
+			want:  map[string]string{"DB_URL": "mongodb://host:27017/prod?replicaSet=prod&w=1"},
+		},
+		{
+			name:  "last assignment wins",
+			input: "FOO=1\nFOO=2\n",
+			want:  map[string]string{"FOO": "2"},
+		},
+		{
+			name:  "malformed lines are skipped",
+			input: "key3\nFOO=\"unterminated\nBAR=ok\nbad key=1\nBAZ=\"x\" y\n",
+			want:  map[string]string{"BAR": "ok"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := Parse(strings.NewReader(test.input))
+			assert.NoError(t, err)
+
+			got := map[string]string{}
+			for key, value := range data {
+				got[key] = string(value)
+			}
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{name: "missing separator", input: "FOO=1\nkey3\n", line: 2},
+		{name: "invalid key", input: "\n\nbad key=1\n", line: 3},
+		{name: "unterminated quote", input: "FOO=1\nBAR=\"abc\n\n", line: 2},
+		{name: "trailing characters", input: "FOO='abc' def\n", line: 1},
+		{name: "trailing characters after multiline", input: "FOO=\"a\nb\" c\n", line: 2},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseStrict(strings.NewReader(test.input))
+
+			var parseErr *ParseError
+			assert.True(t, errors.As(err, &parseErr), "error should be a *ParseError")
+			assert.Equal(t, test.line, parseErr.Line)
+		})
+	}
+}
+
+func TestParseEntriesLineNumbers(t *testing.T) {
+	t.Parallel()
+
+	entries, err := ParseEntries(strings.NewReader("# header\nA=1\nB=\"x\ny\"\nC=3\n"), true)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	assert.Equal(t, "A", entries[0].Key)
+	assert.Equal(t, 2, entries[0].Line)
+	assert.Equal(t, "B", entries[1].Key)
+	assert.Equal(t, 3, entries[1].Line)
+	assert.Equal(t, "C", entries[2].Key)
+	assert.Equal(t, 5, entries[2].Line)
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add("key=value\n")
+	f.Add("export FOO=\"a\\nb\" # comment\n")
+	f.Add("FOO='multi\nline'\n")
+	f.Add("FOO=\"unterminated\n")
+	f.Add("# comment\n\nBAR = baz #x\r\n")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		lax, err := Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("lax parsing should never fail: %v", err)
+		}
+
+		strict, err := ParseStrict(strings.NewReader(input))
+		if err != nil {
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("strict parsing returned unexpected error type: %v", err)
+			}
+			return
+		}
+
+		// input accepted by strict mode must parse identically in lax mode
+		assert.Equal(t, strict, lax)
+		for key := range strict {
+			if !keyPattern.MatchString(key) {
+				t.Fatalf("invalid key %q returned", key)
+			}
+		}
+	})
+}
@@ -0,0 +1,246 @@
+// Package generate creates random Secret values from crypto/rand
+package generate
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Types of generated values
+const (
+	TypePassword = "password"
+	TypeAlnum    = "alnum"
+	TypeHex      = "hex"
+	TypeBase64   = "base64"
+	TypeUUID     = "uuid"
+)
+
+// defaultLengths is the length of each type when none is given. Passwords and
+// alnum values count characters, hex and base64 values count random bytes.
+var defaultLengths = map[string]int{
+	TypePassword: 32,
+	TypeAlnum:    32,
+	TypeHex:      32,
+	TypeBase64:   32,
+	TypeUUID:     0,
+}
+
+// Options of a generator spec, see Parse
+const (
+	optionNoSymbols        = "no-symbols"
+	optionExcludeAmbiguous = "no-ambiguous"
+)
+
+const (
+	lowercase = "abcdefghijklmnopqrstuvwxyz"
+	uppercase = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digits    = "0123456789"
+
+	// symbols leaves out quotes, backslashes and spaces which tend to break
+	// shell scripts and connection strings
+	symbols = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+
+	// ambiguous characters are easily confused when read or typed
+	ambiguous = "Il1O0o|"
+)
+
+// Generator creates random values of one type
+type Generator struct {
+	Type   string
+	Length int
+
+	// NoSymbols leaves symbols out of passwords, which otherwise have at
+	// least one
+	NoSymbols bool
+
+	// ExcludeAmbiguous leaves out characters that are easily confused, such
+	// as l, 1, O and 0
+	ExcludeAmbiguous bool
+}
+
+// Types returns the sorted names of the generator types
+func Types() []string {
+	types := make([]string, 0, len(defaultLengths))
+	for name := range defaultLengths {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// New returns a generator of type with length, the default length of the
+// type is used when length is 0
+func New(genType string, length int) (*Generator, error) {
+	defaultLength, ok := defaultLengths[genType]
+	if !ok {
+		return nil, fmt.Errorf("unknown generator %q, must be one of: %s", genType, strings.Join(Types(), ", "))
+	}
+
+	switch {
+	case genType == TypeUUID && length != 0:
+		return nil, fmt.Errorf("%s values have a fixed length", TypeUUID)
+	case length < 0:
+		return nil, fmt.Errorf("invalid length %d", length)
+	case length == 0:
+		length = defaultLength
+	}
+	return &Generator{Type: genType, Length: length}, nil
+}
+
+// Parse reads a generator spec in the form type[:length][:option...], such as
+// password:32:no-ambiguous. The options are no-symbols and no-ambiguous.
+func Parse(spec string) (*Generator, error) {
+	parts := strings.Split(spec, ":")
+	options := parts[1:]
+
+	length := 0
+	if len(options) > 0 {
+		if n, err := strconv.Atoi(options[0]); err == nil {
+			length = n
+			options = options[1:]
+		}
+	}
+
+	g, err := New(parts[0], length)
+	if err != nil {
+		return nil, err
+	}
+	for _, option := range options {
+		switch option {
+		case optionNoSymbols:
+			g.NoSymbols = true
+		case optionExcludeAmbiguous:
+			g.ExcludeAmbiguous = true
+		default:
+			return nil, fmt.Errorf("unknown generator option %q in %s, must be %s or %s", option, spec, optionNoSymbols, optionExcludeAmbiguous)
+		}
+	}
+	return g, nil
+}
+
+// String returns the spec of the generator, which Parse accepts
+func (g *Generator) String() string {
+	parts := []string{g.Type}
+	if g.Type != TypeUUID {
+		parts = append(parts, strconv.Itoa(g.Length))
+	}
+	if g.NoSymbols && g.Type == TypePassword {
+		parts = append(parts, optionNoSymbols)
+	}
+	if g.ExcludeAmbiguous && (g.Type == TypePassword || g.Type == TypeAlnum) {
+		parts = append(parts, optionExcludeAmbiguous)
+	}
+	return strings.Join(parts, ":")
+}
+
+// Generate returns a new random value
+func (g *Generator) Generate() ([]byte, error) {
+	switch g.Type {
+	case TypePassword:
+		return g.password()
+	case TypeAlnum:
+		return randomString(g.charset(lowercase+uppercase+digits), g.Length)
+	case TypeHex:
+		raw, err := randomBytes(g.Length)
+		return []byte(hex.EncodeToString(raw)), err
+	case TypeBase64:
+		raw, err := randomBytes(g.Length)
+		return []byte(base64.StdEncoding.EncodeToString(raw)), err
+	case TypeUUID:
+		return uuid()
+	}
+	return nil, fmt.Errorf("unknown generator %q", g.Type)
+}
+
+// password has at least one character of each class, the remaining ones are
+// picked from all classes
+func (g *Generator) password() ([]byte, error) {
+	classes := []string{g.charset(lowercase), g.charset(uppercase), g.charset(digits)}
+	if !g.NoSymbols {
+		classes = append(classes, g.charset(symbols))
+	}
+	if g.Length < len(classes) {
+		return nil, fmt.Errorf("passwords must be at least %d characters long", len(classes))
+	}
+
+	value := make([]byte, 0, g.Length)
+	for _, class := range classes {
+		c, err := randomString(class, 1)
+		if err != nil {
+			return nil, err
+		}
+		value = append(value, c...)
+	}
+	rest, err := randomString(strings.Join(classes, ""), g.Length-len(classes))
+	if err != nil {
+		return nil, err
+	}
+	value = append(value, rest...)
+
+	// the required characters must not always be at the start
+	for i := len(value) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		value[i], value[j] = value[j], value[i]
+	}
+	return value, nil
+}
+
+// charset removes the ambiguous characters from chars when required
+func (g *Generator) charset(chars string) string {
+	if !g.ExcludeAmbiguous {
+		return chars
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(ambiguous, r) {
+			return -1
+		}
+		return r
+	}, chars)
+}
+
+func randomBytes(n int) ([]byte, error) {
+	raw := make([]byte, n)
+	_, err := rand.Read(raw)
+	return raw, err
+}
+
+// randomInt returns a uniformly distributed number in [0, limit)
+func randomInt(limit int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(limit)))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()), nil
+}
+
+func randomString(chars string, length int) ([]byte, error) {
+	value := make([]byte, length)
+	for i := range value {
+		j, err := randomInt(len(chars))
+		if err != nil {
+			return nil, err
+		}
+		value[i] = chars[j]
+	}
+	return value, nil
+}
+
+// uuid returns a random version 4 UUID
+func uuid() ([]byte, error) {
+	raw, err := randomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	raw[6] = raw[6]&0x0f | 0x40
+	raw[8] = raw[8]&0x3f | 0x80
+	return []byte(fmt.Sprintf("%x-%x-%x-%x-%x", raw[0:4], raw[4:6], raw[6:8], raw[8:10], raw[10:16])), nil
+}
@@ -0,0 +1,84 @@
+package generate
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for spec, expected := range map[string]string{
+		"password":                         "password:32",
+		"password:16":                      "password:16",
+		"password:16:no-ambiguous":         "password:16:no-ambiguous",
+		"password:no-symbols:no-ambiguous": "password:32:no-symbols:no-ambiguous",
+		"hex:64":                           "hex:64",
+		"alnum:no-symbols":                 "alnum:32",
+		"uuid":                             "uuid",
+	} {
+		g, err := Parse(spec)
+		assert.NoError(t, err, spec)
+		assert.Equal(t, expected, g.String(), spec)
+	}
+
+	for _, spec := range []string{"", "pin", "password:-1", "password:16:upper", "uuid:36"} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	for spec, pattern := range map[string]string{
+		"alnum:20":  `^[a-zA-Z0-9]{20}$`,
+		"hex:16":    `^[0-9a-f]{32}$`,
+		"base64:30": `^[a-zA-Z0-9+/]{40}$`,
+		"uuid":      `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+	} {
+		g, err := Parse(spec)
+		assert.NoError(t, err, spec)
+		value, err := g.Generate()
+		assert.NoError(t, err, spec)
+		assert.Regexp(t, regexp.MustCompile(pattern), string(value), spec)
+	}
+
+	g, err := Parse("alnum:16")
+	assert.NoError(t, err)
+	first, err := g.Generate()
+	assert.NoError(t, err)
+	second, err := g.Generate()
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second, "Generated values should be random")
+}
+
+func TestGeneratePassword(t *testing.T) {
+	t.Parallel()
+
+	g, err := Parse("password:4")
+	assert.NoError(t, err)
+	for i := 0; i < 50; i++ {
+		value, err := g.Generate()
+		assert.NoError(t, err)
+		assert.Len(t, value, 4)
+		for _, class := range []string{lowercase, uppercase, digits, symbols} {
+			assert.True(t, strings.ContainsAny(string(value), class), "Passwords should contain a character of each class: %s", value)
+		}
+	}
+
+	g, err = Parse("password:64:no-symbols:no-ambiguous")
+	assert.NoError(t, err)
+	value, err := g.Generate()
+	assert.NoError(t, err)
+	assert.False(t, strings.ContainsAny(string(value), symbols), "Passwords without symbols should not contain any")
+	assert.False(t, strings.ContainsAny(string(value), ambiguous), "Ambiguous characters should be excluded")
+
+	g, err = Parse("password:3")
+	assert.NoError(t, err)
+	_, err = g.Generate()
+	assert.EqualError(t, err, "passwords must be at least 4 characters long")
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+)
+
+// ChangeType describes what happens to a Secret key
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeChanged ChangeType = "changed"
+	ChangeRemoved ChangeType = "removed"
+)
+
+// KeyChange is the planned change of a single Secret key
+type KeyChange struct {
+	Key  string
+	Type ChangeType
+	Old  []byte
+	New  []byte
+}
+
+// Diff compares the current data of a Secret to the desired data and returns
+// the changes sorted by key. Keys with identical values are omitted.
+func Diff(current, desired map[string][]byte) []KeyChange {
+	var changes []KeyChange
+
+	for key, value := range desired {
+		old, ok := current[key]
+		switch {
+		case !ok:
+			changes = append(changes, KeyChange{Key: key, Type: ChangeAdded, New: value})
+		case !bytes.Equal(old, value):
+			changes = append(changes, KeyChange{Key: key, Type: ChangeChanged, Old: old, New: value})
+		}
+	}
+
+	for key, value := range current {
+		if _, ok := desired[key]; !ok {
+			changes = append(changes, KeyChange{Key: key, Type: ChangeRemoved, Old: value})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// MergeData returns a copy of current with data applied on top and keys removed
+func MergeData(current, data map[string][]byte, remove []string) map[string][]byte {
+	merged := make(map[string][]byte, len(current)+len(data))
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range data {
+		merged[key] = value
+	}
+	for _, key := range remove {
+		delete(merged, key)
+	}
+	return merged
+}
+
+// MaxSecretSize is the largest total size of the values of a Secret accepted
+// by the API server
+const MaxSecretSize = 1024 * 1024
+
+// DataSize returns the total size of the values in data, as counted against
+// MaxSecretSize
+func DataSize(data map[string][]byte) int {
+	size := 0
+	for _, value := range data {
+		size += len(value)
+	}
+	return size
+}
+
+// Fingerprint returns the hex encoded SHA-256 digest of a value, which lets
+// values be compared without revealing them
+func Fingerprint(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	current := map[string][]byte{
+		"same":    []byte("value"),
+		"changed": []byte("old"),
+		"removed": []byte("value"),
+	}
+	desired := map[string][]byte{
+		"same":    []byte("value"),
+		"changed": []byte("new"),
+		"added":   []byte("value"),
+	}
+
+	assert.Equal(t, []KeyChange{
+		{Key: "added", Type: ChangeAdded, New: []byte("value")},
+		{Key: "changed", Type: ChangeChanged, Old: []byte("old"), New: []byte("new")},
+		{Key: "removed", Type: ChangeRemoved, Old: []byte("value")},
+	}, Diff(current, desired))
+
+	assert.Empty(t, Diff(current, current))
+}
+
+func TestMergeData(t *testing.T) {
+	t.Parallel()
+
+	current := map[string][]byte{"a": []byte("1"), "b": []byte("2")}
+	merged := MergeData(current, map[string][]byte{"b": []byte("3"), "c": []byte("4")}, []string{"a"})
+
+	assert.Equal(t, map[string][]byte{"b": []byte("3"), "c": []byte("4")}, merged)
+	assert.Equal(t, "1", string(current["a"]), "current data should not be modified")
+}
+
+func TestDataSize(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, 0, DataSize(nil))
+	assert.Equal(t, 5, DataSize(map[string][]byte{"a": []byte("12"), "b": []byte("345")}))
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", Fingerprint(nil))
+	assert.NotEqual(t, Fingerprint([]byte("a")), Fingerprint([]byte("b")))
+}
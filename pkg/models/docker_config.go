@@ -0,0 +1,148 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// DockerConfigEntry holds the credentials of a single registry
+type DockerConfigEntry struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+}
+
+// NewDockerConfigEntry constructor, auth is derived from username and password
+func NewDockerConfigEntry(username, password, email string) DockerConfigEntry {
+	return DockerConfigEntry{
+		Username: username,
+		Password: password,
+		Email:    email,
+		Auth:     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+	}
+}
+
+// DockerConfig is the content of a .dockerconfigjson key. Registries are kept
+// as raw JSON so fields ksec does not know about, such as identity tokens,
+// are preserved when other registries change.
+type DockerConfig struct {
+	fields map[string]json.RawMessage
+	auths  map[string]json.RawMessage
+}
+
+// ParseDockerConfig reads a .dockerconfigjson document, empty data is an
+// empty config
+func ParseDockerConfig(data []byte) (*DockerConfig, error) {
+	config := &DockerConfig{
+		fields: make(map[string]json.RawMessage),
+		auths:  make(map[string]json.RawMessage),
+	}
+	if len(data) == 0 {
+		return config, nil
+	}
+
+	if err := json.Unmarshal(data, &config.fields); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", v1.DockerConfigJsonKey, err)
+	}
+	if raw, ok := config.fields["auths"]; ok {
+		if err := json.Unmarshal(raw, &config.auths); err != nil {
+			return nil, fmt.Errorf("invalid auths in %s: %w", v1.DockerConfigJsonKey, err)
+		}
+	}
+	return config, nil
+}
+
+// Servers returns the sorted registry servers in the config
+func (c *DockerConfig) Servers() []string {
+	servers := make([]string, 0, len(c.auths))
+	for server := range c.auths {
+		servers = append(servers, server)
+	}
+	sort.Strings(servers)
+	return servers
+}
+
+// Entry returns the credentials of server. The username is read from auth
+// when it is not set on its own.
+func (c *DockerConfig) Entry(server string) (DockerConfigEntry, bool, error) {
+	entry := DockerConfigEntry{}
+	raw, ok := c.auths[server]
+	if !ok {
+		return entry, false, nil
+	}
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return entry, true, fmt.Errorf("invalid credentials for %s: %w", server, err)
+	}
+
+	if entry.Username == "" && entry.Auth != "" {
+		if decoded, err := base64.StdEncoding.DecodeString(entry.Auth); err == nil {
+			if username, _, ok := strings.Cut(string(decoded), ":"); ok {
+				entry.Username = username
+			}
+		}
+	}
+	return entry, true, nil
+}
+
+// Set adds or replaces the credentials of server
+func (c *DockerConfig) Set(server string, entry DockerConfigEntry) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	c.auths[server] = raw
+	return nil
+}
+
+// Remove deletes the credentials of server and reports whether it was present
+func (c *DockerConfig) Remove(server string) bool {
+	_, ok := c.auths[server]
+	delete(c.auths, server)
+	return ok
+}
+
+// Marshal returns the config as a .dockerconfigjson document
+func (c *DockerConfig) Marshal() ([]byte, error) {
+	auths, err := json.Marshal(c.auths)
+	if err != nil {
+		return nil, err
+	}
+	c.fields["auths"] = auths
+	return json.Marshal(c.fields)
+}
+
+// AttachImagePullSecret adds the Secret name to the imagePullSecrets of a
+// ServiceAccount and reports whether it had to be added
+func (s *SecretsClient) AttachImagePullSecret(ctx context.Context, serviceAccount, name string) (bool, error) {
+	accounts := s.clientSet.CoreV1().ServiceAccounts(s.Namespace)
+	attached := false
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		account, err := accounts.Get(ctx, serviceAccount, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		for _, ref := range account.ImagePullSecrets {
+			if ref.Name == name {
+				return nil
+			}
+		}
+
+		account.ImagePullSecrets = append(account.ImagePullSecrets, v1.LocalObjectReference{Name: name})
+		_, err = accounts.Update(ctx, account, metav1.UpdateOptions{DryRun: s.dryRunOption(), FieldManager: FieldManager})
+		if err == nil {
+			attached = true
+		}
+		return err
+	})
+	return attached, err
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDockerConfig(t *testing.T) {
+	config, err := ParseDockerConfig([]byte(`{"auths":{"quay.io":{"auth":"cm9ib3Q6c2VjcmV0","identitytoken":"abc"}},"credsStore":"desktop"}`))
+	assert.NoError(t, err)
+
+	entry, ok, err := config.Entry("quay.io")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "robot", entry.Username, "The username should be read from auth")
+
+	assert.NoError(t, config.Set("ghcr.io", NewDockerConfigEntry("bot", "token", "")))
+	assert.Equal(t, []string{"ghcr.io", "quay.io"}, config.Servers())
+
+	out, err := config.Marshal()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"auths": {
+			"ghcr.io": {"username": "bot", "password": "token", "auth": "Ym90OnRva2Vu"},
+			"quay.io": {"auth": "cm9ib3Q6c2VjcmV0", "identitytoken": "abc"}
+		},
+		"credsStore": "desktop"
+	}`, string(out), "Other registries and fields should be kept")
+
+	assert.True(t, config.Remove("quay.io"))
+	assert.False(t, config.Remove("quay.io"))
+	_, ok, err = config.Entry("quay.io")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = ParseDockerConfig([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestAttachImagePullSecret(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	ctx := context.Background()
+
+	accounts := secretsClient.clientSet.CoreV1().ServiceAccounts(defaultNamespace)
+	_, err := accounts.Create(ctx, &v1.ServiceAccount{
+		ObjectMeta:       metav1.ObjectMeta{Name: "default"},
+		ImagePullSecrets: []v1.LocalObjectReference{{Name: "existing"}},
+	}, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	attached, err := secretsClient.AttachImagePullSecret(ctx, "default", "regcred")
+	assert.NoError(t, err)
+	assert.True(t, attached)
+
+	attached, err = secretsClient.AttachImagePullSecret(ctx, "default", "regcred")
+	assert.NoError(t, err)
+	assert.False(t, attached, "An attached Secret should not be added twice")
+
+	account, err := accounts.Get(ctx, "default", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []v1.LocalObjectReference{{Name: "existing"}, {Name: "regcred"}}, account.ImagePullSecrets)
+
+	_, err = secretsClient.AttachImagePullSecret(ctx, "missing", "regcred")
+	assert.Error(t, err)
+}
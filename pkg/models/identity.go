@@ -0,0 +1,218 @@
+package models
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"strings"
+	"sync"
+
+	authv1 "k8s.io/api/authentication/v1"
+	authv1alpha1 "k8s.io/api/authentication/v1alpha1"
+	authv1beta1 "k8s.io/api/authentication/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Identity is the user the API server authenticates the client as
+type Identity struct {
+	Username string   `json:"username"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// identityResolver looks up the Identity once and shares it between copies
+// of a SecretsClient
+type identityResolver struct {
+	once     sync.Once
+	identity Identity
+	resolve  func(ctx context.Context) Identity
+}
+
+func (r *identityResolver) get(ctx context.Context) Identity {
+	r.once.Do(func() {
+		r.identity = r.resolve(ctx)
+	})
+	return r.identity
+}
+
+// newIdentityResolver asks the API server who the client is. When the
+// SelfSubjectReview API is unavailable the identity is read from the OIDC or
+// service account token, or the client certificate, and as a last resort the
+// kubeconfig user name is used.
+func newIdentityResolver(clientSet kubernetes.Interface, config *rest.Config, authInfo string) *identityResolver {
+	return &identityResolver{
+		resolve: func(ctx context.Context) Identity {
+			if identity, ok := selfSubjectReview(ctx, clientSet); ok {
+				return identity
+			}
+			if identity, ok := configIdentity(config); ok {
+				return identity
+			}
+			return Identity{Username: authInfo}
+		},
+	}
+}
+
+// selfSubjectReview tries every served version of the SelfSubjectReview API
+func selfSubjectReview(ctx context.Context, clientSet kubernetes.Interface) (Identity, bool) {
+	// authentication.k8s.io/v1 is newer than this client library, so it is
+	// requested without typed helpers
+	if restClient, ok := clientSet.AuthenticationV1().RESTClient().(*rest.RESTClient); ok && restClient != nil {
+		body := []byte(`{"apiVersion":"authentication.k8s.io/v1","kind":"SelfSubjectReview"}`)
+		raw, err := restClient.Post().Resource("selfsubjectreviews").Body(body).DoRaw(ctx)
+		review := authv1beta1.SelfSubjectReview{}
+		if err == nil && json.Unmarshal(raw, &review) == nil {
+			if identity, ok := userInfoIdentity(review.Status.UserInfo); ok {
+				return identity, true
+			}
+		}
+	}
+
+	beta, err := clientSet.AuthenticationV1beta1().SelfSubjectReviews().Create(ctx, &authv1beta1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err == nil {
+		if identity, ok := userInfoIdentity(beta.Status.UserInfo); ok {
+			return identity, true
+		}
+	}
+
+	alpha, err := clientSet.AuthenticationV1alpha1().SelfSubjectReviews().Create(ctx, &authv1alpha1.SelfSubjectReview{}, metav1.CreateOptions{})
+	if err == nil {
+		if identity, ok := userInfoIdentity(alpha.Status.UserInfo); ok {
+			return identity, true
+		}
+	}
+
+	return Identity{}, false
+}
+
+func userInfoIdentity(userInfo authv1.UserInfo) (Identity, bool) {
+	if userInfo.Username == "" {
+		return Identity{}, false
+	}
+	return Identity{Username: userInfo.Username, Groups: userInfo.Groups}, true
+}
+
+// configIdentity reads the identity from the credentials in config
+func configIdentity(config *rest.Config) (Identity, bool) {
+	if claims, ok := parseTokenClaims(configToken(config)); ok {
+		username := claims.Subject
+		if claims.Email != "" {
+			username = claims.Email
+		}
+		if username != "" {
+			return Identity{Username: username, Groups: claims.Groups}, true
+		}
+	}
+
+	if cert, ok := clientCertificate(config); ok && cert.Subject.CommonName != "" {
+		return Identity{Username: cert.Subject.CommonName, Groups: cert.Subject.Organization}, true
+	}
+
+	return Identity{}, false
+}
+
+// tokenClaims are the JWT claims used to identify the token owner
+type tokenClaims struct {
+	Subject string   `json:"sub"`
+	Email   string   `json:"email"`
+	Groups  []string `json:"groups"`
+}
+
+// authInfoName returns a name for the identity the client authenticates as,
+// used until the Identity is resolved. Service account tokens, as used in pods
+// and CI jobs, identify as system:serviceaccount:<namespace>:<name>.
+func authInfoName(opts *ClientOptions, rawConfig api.Config, kubeContext string, config *rest.Config) string {
+	if opts.Impersonate != "" {
+		return opts.Impersonate
+	}
+
+	context, ok := rawConfig.Contexts[kubeContext]
+	if ok && opts.Token == "" {
+		return context.AuthInfo
+	}
+	if subject := tokenSubject(config); subject != "" {
+		return subject
+	}
+	if ok {
+		return context.AuthInfo
+	}
+	return ""
+}
+
+// tokenSubject returns the subject of the bearer token in config when it is a
+// JWT, such as service account and OIDC tokens
+func tokenSubject(config *rest.Config) string {
+	claims, ok := parseTokenClaims(configToken(config))
+	if !ok {
+		return ""
+	}
+	if claims.Email != "" {
+		return claims.Email
+	}
+	return claims.Subject
+}
+
+// configToken returns the bearer or OIDC id token used by config
+func configToken(config *rest.Config) string {
+	if config.BearerToken != "" {
+		return config.BearerToken
+	}
+	if config.BearerTokenFile != "" {
+		raw, err := os.ReadFile(config.BearerTokenFile)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(raw))
+	}
+	if config.AuthProvider != nil && config.AuthProvider.Name == "oidc" {
+		return config.AuthProvider.Config["id-token"]
+	}
+	return ""
+}
+
+// parseTokenClaims decodes the payload of a JWT without verifying it, the API
+// server is responsible for that
+func parseTokenClaims(token string) (*tokenClaims, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, false
+	}
+
+	claims := &tokenClaims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+// clientCertificate returns the leaf client certificate used by config
+func clientCertificate(config *rest.Config) (*x509.Certificate, bool) {
+	data := config.CertData
+	if len(data) == 0 && config.CertFile != "" {
+		raw, err := os.ReadFile(config.CertFile)
+		if err != nil {
+			return nil, false
+		}
+		data = raw
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, false
+	}
+	return cert, true
+}
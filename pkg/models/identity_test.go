@@ -0,0 +1,153 @@
+package models
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	authv1 "k8s.io/api/authentication/v1"
+	authv1alpha1 "k8s.io/api/authentication/v1alpha1"
+	authv1beta1 "k8s.io/api/authentication/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+func testToken(claims string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(claims))
+	return header + "." + payload + ".signature"
+}
+
+func TestTokenSubject(t *testing.T) {
+	t.Parallel()
+
+	serviceAccount := testToken(`{"sub":"system:serviceaccount:ci:deployer"}`)
+	assert.Equal(t, "system:serviceaccount:ci:deployer", tokenSubject(&rest.Config{BearerToken: serviceAccount}))
+
+	oidc := testToken(`{"sub":"1234567890","email":"jane@example.com"}`)
+	assert.Equal(t, "jane@example.com", tokenSubject(&rest.Config{BearerToken: oidc}))
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	assert.NoError(t, os.WriteFile(tokenFile, []byte(serviceAccount+"\n"), 0600))
+	assert.Equal(t, "system:serviceaccount:ci:deployer", tokenSubject(&rest.Config{BearerTokenFile: tokenFile}))
+
+	assert.Empty(t, tokenSubject(&rest.Config{BearerToken: "opaque-token"}))
+	assert.Empty(t, tokenSubject(&rest.Config{}))
+}
+
+func TestAuthInfoName(t *testing.T) {
+	t.Parallel()
+
+	rawConfig := api.Config{Contexts: map[string]*api.Context{"dev": {AuthInfo: "dev-user"}}}
+	config := &rest.Config{BearerToken: testToken(`{"sub":"system:serviceaccount:ci:deployer"}`)}
+
+	assert.Equal(t, "dev-user", authInfoName(&ClientOptions{}, rawConfig, "dev", config))
+	assert.Equal(t, "jane", authInfoName(&ClientOptions{Impersonate: "jane"}, rawConfig, "dev", config))
+	assert.Equal(t, "system:serviceaccount:ci:deployer", authInfoName(&ClientOptions{Token: "token"}, rawConfig, "dev", config))
+
+	// no current context, e.g. in a pod with only a service account token
+	assert.Equal(t, "system:serviceaccount:ci:deployer", authInfoName(&ClientOptions{}, api.Config{}, "", config))
+	assert.Equal(t, "", authInfoName(&ClientOptions{}, api.Config{}, "", &rest.Config{}))
+}
+
+func TestSelfSubjectReview(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	userInfo := authv1.UserInfo{Username: "jane@example.com", Groups: []string{"admins", "system:authenticated"}}
+
+	beta := testclient.NewSimpleClientset()
+	beta.PrependReactor("create", "selfsubjectreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetResource().Version != "v1beta1" {
+			return true, nil, errors.New("not served")
+		}
+		return true, &authv1beta1.SelfSubjectReview{Status: authv1beta1.SelfSubjectReviewStatus{UserInfo: userInfo}}, nil
+	})
+	identity, ok := selfSubjectReview(ctx, beta)
+	assert.True(t, ok)
+	assert.Equal(t, Identity{Username: "jane@example.com", Groups: []string{"admins", "system:authenticated"}}, identity)
+
+	alpha := testclient.NewSimpleClientset()
+	alpha.PrependReactor("create", "selfsubjectreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetResource().Version != "v1alpha1" {
+			return true, nil, errors.New("not served")
+		}
+		return true, &authv1alpha1.SelfSubjectReview{Status: authv1alpha1.SelfSubjectReviewStatus{UserInfo: userInfo}}, nil
+	})
+	identity, ok = selfSubjectReview(ctx, alpha)
+	assert.True(t, ok)
+	assert.Equal(t, "jane@example.com", identity.Username)
+
+	_, ok = selfSubjectReview(ctx, testclient.NewSimpleClientset())
+	assert.False(t, ok, "An empty review should not be treated as an identity")
+}
+
+func TestConfigIdentity(t *testing.T) {
+	t.Parallel()
+
+	identity, ok := configIdentity(&rest.Config{
+		AuthProvider: &api.AuthProviderConfig{
+			Name:   "oidc",
+			Config: map[string]string{"id-token": testToken(`{"sub":"123","email":"jane@example.com","groups":["devs"]}`)},
+		},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, Identity{Username: "jane@example.com", Groups: []string{"devs"}}, identity)
+
+	identity, ok = configIdentity(&rest.Config{TLSClientConfig: rest.TLSClientConfig{CertData: testCertificate(t, "kubernetes-admin", "system:masters")}})
+	assert.True(t, ok)
+	assert.Equal(t, Identity{Username: "kubernetes-admin", Groups: []string{"system:masters"}}, identity)
+
+	_, ok = configIdentity(&rest.Config{BearerToken: "opaque-token"})
+	assert.False(t, ok)
+}
+
+func TestIdentityAnnotation(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	ctx := context.Background()
+
+	assert.Equal(t, Identity{Username: "testuser"}, secretsClient.Identity(ctx), "Identity should fall back to AuthInfo")
+
+	setupTestClient(defaultNamespace)
+	secretsClient.clientSet.(*testclient.Clientset).PrependReactor("create", "selfsubjectreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &authv1beta1.SelfSubjectReview{Status: authv1beta1.SelfSubjectReviewStatus{UserInfo: authv1.UserInfo{Username: "jane", Groups: []string{"devs"}}}}, nil
+	})
+
+	secret, err := secretsClient.CreateWithData(ctx, expectedSecretName, map[string][]byte{"key": []byte("value")})
+	assert.NoError(t, err)
+
+	annotation, err := GetKeyAnnotation(secret, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, "jane", annotation.UpdatedBy)
+	assert.Equal(t, []string{"devs"}, annotation.Groups)
+}
+
+func testCertificate(t *testing.T, commonName string, organization ...string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName, Organization: organization},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
@@ -1,15 +1,60 @@
 package models
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
 	"time"
+
+	"github.com/kanopy-platform/ksec/internal/version"
+	v1 "k8s.io/api/core/v1"
 )
 
 const annotationPrefix = "ksec.io"
 
-// KeyAnnotation holds metadata about individual Secrets keys
+// fingerprintSaltSize is the number of random bytes mixed into value fingerprints
+const fingerprintSaltSize = 16
+
+// Operations recorded in key annotations
+const (
+	OperationSet      = "set"
+	OperationPush     = "push"
+	OperationTLS      = "tls"
+	OperationRegistry = "registry"
+	OperationEdit     = "edit"
+	OperationGenerate = "generate"
+	OperationRotate   = "rotate"
+)
+
+// KeyAnnotation holds metadata about individual Secrets keys. Fields added
+// after updatedBy and lastUpdated are optional so older annotations still parse.
 type KeyAnnotation struct {
-	UpdatedBy   string `json:"updatedBy"`
-	LastUpdated string `json:"lastUpdated"`
+	UpdatedBy   string   `json:"updatedBy"`
+	Groups      []string `json:"groups,omitempty"`
+	LastUpdated string   `json:"lastUpdated"`
+	Operation   string   `json:"operation,omitempty"`
+	Source      string   `json:"source,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	Hostname    string   `json:"hostname,omitempty"`
+
+	// Generator is the spec of the generator of a random value, such as
+	// password:32
+	Generator string `json:"generator,omitempty"`
+
+	// RotatedAt and RotatedBy record the last rotation of the value, the
+	// previous value is kept in another key until the rotation is finished
+	RotatedAt string `json:"rotatedAt,omitempty"`
+	RotatedBy string `json:"rotatedBy,omitempty"`
+
+	// Fingerprint is a salted SHA-256 digest of the value in the form
+	// sha256:<salt>:<digest>, which tells whether a value changed without revealing it
+	Fingerprint string `json:"fingerprint,omitempty"`
 }
 
 // NewKeyAnnotation constructor
@@ -19,3 +64,112 @@ func NewKeyAnnotation(authInfo string) *KeyAnnotation {
 		LastUpdated: time.Now().Format(time.RFC3339),
 	}
 }
+
+// ChangeInfo describes how keys are being changed, it is recorded in the
+// annotations of every key written with a context carrying it
+type ChangeInfo struct {
+	Operation string
+	Source    string
+
+	// KeySources overrides Source for individual keys
+	KeySources map[string]string
+
+	// KeyGenerators records the generator of keys with random values
+	KeyGenerators map[string]string
+
+	// RotatedKeys are the keys whose value is being rotated
+	RotatedKeys map[string]bool
+}
+
+type changeInfoKey struct{}
+
+// WithChangeInfo returns a context that records info in key annotations
+func WithChangeInfo(ctx context.Context, info ChangeInfo) context.Context {
+	return context.WithValue(ctx, changeInfoKey{}, info)
+}
+
+func changeInfoFrom(ctx context.Context) ChangeInfo {
+	info, _ := ctx.Value(changeInfoKey{}).(ChangeInfo)
+	return info
+}
+
+// SetFingerprint stores a newly salted fingerprint of value
+func (a *KeyAnnotation) SetFingerprint(value []byte) error {
+	salt := make([]byte, fingerprintSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	a.Fingerprint = fmt.Sprintf("sha256:%s:%s", hex.EncodeToString(salt), saltedDigest(salt, value))
+	return nil
+}
+
+// MatchesValue reports whether value is the one the fingerprint was taken of
+func (a *KeyAnnotation) MatchesValue(value []byte) bool {
+	parts := strings.Split(a.Fingerprint, ":")
+	if len(parts) != 3 || parts[0] != "sha256" {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(parts[2]), []byte(saltedDigest(salt, value))) == 1
+}
+
+func saltedDigest(salt, value []byte) string {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write(value)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GetKeyAnnotation returns the metadata stored for a Secret key, or nil when
+// the key has no annotation
+func GetKeyAnnotation(secret *v1.Secret, key string) (*KeyAnnotation, error) {
+	raw, ok := secret.Annotations[fmt.Sprintf("%s/%s", annotationPrefix, key)]
+	if !ok || raw == "" {
+		return nil, nil
+	}
+
+	annotation := &KeyAnnotation{}
+	if err := json.Unmarshal([]byte(raw), annotation); err != nil {
+		return nil, fmt.Errorf("invalid annotation for key %s: %w", key, err)
+	}
+	return annotation, nil
+}
+
+// hostname of the machine ksec runs on, empty when it cannot be determined
+func hostname() string {
+	name, _ := os.Hostname()
+	return name
+}
+
+// keyAnnotationJSON builds the serialized annotation for a changed key
+func (s *SecretsClient) keyAnnotationJSON(ctx context.Context, key string, value []byte) (string, error) {
+	identity := s.Identity(ctx)
+	info := changeInfoFrom(ctx)
+
+	annotation := NewKeyAnnotation(identity.Username)
+	annotation.Groups = identity.Groups
+	annotation.Operation = info.Operation
+	annotation.Source = info.Source
+	if source, ok := info.KeySources[key]; ok {
+		annotation.Source = source
+	}
+	annotation.Generator = info.KeyGenerators[key]
+	if info.RotatedKeys[key] {
+		annotation.RotatedAt = annotation.LastUpdated
+		annotation.RotatedBy = identity.Username
+	}
+	annotation.Version = version.Get().Version
+	annotation.Hostname = hostname()
+	if err := annotation.SetFingerprint(value); err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(annotation)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
@@ -1,8 +1,13 @@
 package models
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestNewKeyAnnotation(t *testing.T) {
@@ -12,3 +17,72 @@ func TestNewKeyAnnotation(t *testing.T) {
 		t.Errorf(err.Error())
 	}
 }
+
+func TestGetKeyAnnotation(t *testing.T) {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"ksec.io/key":     `{"updatedBy":"testuser","lastUpdated":"2023-01-01T00:00:00Z"}`,
+				"ksec.io/invalid": `not json`,
+			},
+		},
+	}
+
+	annotation, err := GetKeyAnnotation(secret, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, &KeyAnnotation{UpdatedBy: "testuser", LastUpdated: "2023-01-01T00:00:00Z"}, annotation)
+
+	annotation, err = GetKeyAnnotation(secret, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, annotation)
+
+	_, err = GetKeyAnnotation(secret, "invalid")
+	assert.Error(t, err)
+}
+
+func TestKeyAnnotationFingerprint(t *testing.T) {
+	a := &KeyAnnotation{}
+	assert.False(t, a.MatchesValue([]byte("value")))
+
+	assert.NoError(t, a.SetFingerprint([]byte("value")))
+	assert.Regexp(t, `^sha256:[0-9a-f]{32}:[0-9a-f]{64}$`, a.Fingerprint)
+	assert.True(t, a.MatchesValue([]byte("value")))
+	assert.False(t, a.MatchesValue([]byte("other")))
+
+	// the salt keeps equal values from having equal fingerprints
+	b := &KeyAnnotation{}
+	assert.NoError(t, b.SetFingerprint([]byte("value")))
+	assert.NotEqual(t, a.Fingerprint, b.Fingerprint)
+	assert.True(t, b.MatchesValue([]byte("value")))
+}
+
+func TestChangeInfoAnnotation(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	ctx := WithChangeInfo(context.Background(), ChangeInfo{
+		Operation:     OperationPush,
+		Source:        ".env",
+		KeySources:    map[string]string{"other": "cert.pem"},
+		KeyGenerators: map[string]string{"key": "password:32"},
+		RotatedKeys:   map[string]bool{"key": true},
+	})
+
+	secret, err := secretsClient.CreateWithData(ctx, "info", map[string][]byte{"key": []byte("value"), "other": []byte("pem")})
+	assert.NoError(t, err)
+
+	annotation, err := GetKeyAnnotation(secret, "key")
+	assert.NoError(t, err)
+	assert.Equal(t, OperationPush, annotation.Operation)
+	assert.Equal(t, ".env", annotation.Source)
+	assert.Equal(t, hostname(), annotation.Hostname)
+	assert.True(t, annotation.MatchesValue([]byte("value")))
+	assert.Equal(t, "password:32", annotation.Generator)
+	assert.Equal(t, annotation.LastUpdated, annotation.RotatedAt)
+	assert.Equal(t, annotation.UpdatedBy, annotation.RotatedBy)
+
+	annotation, err = GetKeyAnnotation(secret, "other")
+	assert.NoError(t, err)
+	assert.Equal(t, "cert.pem", annotation.Source)
+	assert.True(t, annotation.MatchesValue([]byte("pem")))
+	assert.Empty(t, annotation.Generator)
+	assert.Empty(t, annotation.RotatedAt)
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// Mutation is the set of per-key changes a command intends to make to a Secret
+type Mutation struct {
+	Set    map[string][]byte
+	Remove []string
+}
+
+// ConflictError is returned when keys a Mutation changes were also changed by
+// someone else since the Secret was read
+type ConflictError struct {
+	Name string
+	Keys []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("secret %s was modified concurrently, keys changed since it was read: %s", e.Name, strings.Join(e.Keys, ", "))
+}
+
+// conflicts returns the sorted keys the mutation changes whose value in
+// latest differs from the one in base and from the intended value
+func (m Mutation) conflicts(base, latest *v1.Secret) []string {
+	var keys []string
+
+	for key, value := range m.Set {
+		current, ok := latest.Data[key]
+		if ok && bytes.Equal(current, value) {
+			continue
+		}
+		if !sameValue(base.Data, latest.Data, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	for _, key := range m.Remove {
+		if _, ok := latest.Data[key]; !ok {
+			continue
+		}
+		if !sameValue(base.Data, latest.Data, key) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+func sameValue(a, b map[string][]byte, key string) bool {
+	x, okA := a[key]
+	y, okB := b[key]
+	return okA == okB && bytes.Equal(x, y)
+}
+
+// Apply makes the changes of m to the Secret base was read from, using the
+// write mode of the client. With WriteModeUpdate, when the Secret changed in
+// the meantime the update is retried on the latest version, unless one of the
+// changed keys was also modified concurrently, in which case a ConflictError
+// is returned instead of overwriting the other change. Changes that would
+// leave out keys the Secret type requires are refused.
+func (s *SecretsClient) Apply(ctx context.Context, base *v1.Secret, m Mutation) (*v1.Secret, error) {
+	if err := ValidateSecretData(base.Type, MergeData(base.Data, m.Set, m.Remove)); err != nil {
+		return nil, err
+	}
+
+	switch s.writeMode {
+	case WriteModePatch:
+		return s.Patch(ctx, base, m)
+	case WriteModeApply:
+		return s.ServerSideApply(ctx, base, m)
+	}
+
+	var result *v1.Secret
+	latest := base
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if latest == nil {
+			secret, err := s.Get(ctx, base.Name)
+			if err != nil {
+				return err
+			}
+			if keys := m.conflicts(base, secret); len(keys) > 0 {
+				return &ConflictError{Name: base.Name, Keys: keys}
+			}
+			latest = secret
+		}
+
+		secret := latest.DeepCopy()
+		RemoveKeys(secret, m.Remove)
+
+		var err error
+		result, err = s.Update(ctx, secret, m.Set)
+		if err != nil {
+			latest = nil
+		}
+		return err
+	})
+	return result, err
+}
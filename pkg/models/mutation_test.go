@@ -0,0 +1,92 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// conflictOnce makes the first update fail with a conflict after another
+// writer changed the stored Secret with modify
+func conflictOnce(t *testing.T, modify func(secret *v1.Secret)) {
+	clientSet := secretsClient.clientSet.(*testclient.Clientset)
+	conflicted := false
+
+	clientSet.PrependReactor("update", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if conflicted {
+			return false, nil, nil
+		}
+		conflicted = true
+
+		update := action.(k8stesting.UpdateAction)
+		obj, err := clientSet.Tracker().Get(update.GetResource(), update.GetNamespace(), expectedSecretName)
+		assert.NoError(t, err)
+		secret := obj.(*v1.Secret).DeepCopy()
+		modify(secret)
+		assert.NoError(t, clientSet.Tracker().Update(update.GetResource(), secret, update.GetNamespace()))
+
+		return true, nil, apierrors.NewConflict(update.GetResource().GroupResource(), expectedSecretName, nil)
+	})
+}
+
+func TestApplyRetriesOnConflict(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	ctx := context.Background()
+
+	base, err := secretsClient.CreateWithData(ctx, expectedSecretName, map[string][]byte{
+		"mine":   []byte("old"),
+		"theirs": []byte("old"),
+		"gone":   []byte("old"),
+	})
+	assert.NoError(t, err)
+
+	conflictOnce(t, func(secret *v1.Secret) {
+		secret.Data["theirs"] = []byte("concurrent")
+		secret.Data["added"] = []byte("concurrent")
+	})
+
+	secret, err := secretsClient.Apply(ctx, base, Mutation{Set: map[string][]byte{"mine": []byte("new")}, Remove: []string{"gone"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"mine":   []byte("new"),
+		"theirs": []byte("concurrent"),
+		"added":  []byte("concurrent"),
+	}, secret.Data, "Concurrent changes to other keys should be kept")
+	assert.Equal(t, []byte("old"), base.Data["gone"], "The base Secret should not be modified")
+}
+
+func TestApplyReportsConcurrentKeyChanges(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	ctx := context.Background()
+
+	base, err := secretsClient.CreateWithData(ctx, expectedSecretName, map[string][]byte{
+		"key":  []byte("old"),
+		"same": []byte("old"),
+		"gone": []byte("old"),
+	})
+	assert.NoError(t, err)
+
+	conflictOnce(t, func(secret *v1.Secret) {
+		secret.Data["key"] = []byte("concurrent")
+		secret.Data["same"] = []byte("new")
+		secret.Data["gone"] = []byte("concurrent")
+	})
+
+	_, err = secretsClient.Apply(ctx, base, Mutation{
+		Set:    map[string][]byte{"key": []byte("new"), "same": []byte("new")},
+		Remove: []string{"gone"},
+	})
+	conflict := &ConflictError{}
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, []string{"gone", "key"}, conflict.Keys, "Keys concurrently changed to the intended value are not conflicts")
+
+	secret, err := secretsClient.Get(ctx, expectedSecretName)
+	assert.NoError(t, err)
+	assert.Equal(t, "concurrent", string(secret.Data["key"]), "The concurrent change should not be overwritten")
+}
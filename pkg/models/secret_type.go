@@ -0,0 +1,105 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// secretTypeNames are the short names of the built-in Secret types ksec can
+// write. Service account tokens are left out, the token controller fills them
+// in for a service account named in an annotation ksec does not set.
+var secretTypeNames = map[string]v1.SecretType{
+	"opaque":           v1.SecretTypeOpaque,
+	"tls":              v1.SecretTypeTLS,
+	"dockerconfigjson": v1.SecretTypeDockerConfigJson,
+	"dockercfg":        v1.SecretTypeDockercfg,
+	"basic-auth":       v1.SecretTypeBasicAuth,
+	"ssh-auth":         v1.SecretTypeSSHAuth,
+	"bootstrap-token":  v1.SecretTypeBootstrapToken,
+}
+
+// SecretTypeNames returns the sorted short names accepted by ParseSecretType
+func SecretTypeNames() []string {
+	names := make([]string, 0, len(secretTypeNames))
+	for name := range secretTypeNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseSecretType returns the built-in Secret type for a short name such as
+// tls, or a full type name such as kubernetes.io/tls
+func ParseSecretType(name string) (v1.SecretType, error) {
+	if secretType, ok := secretTypeNames[strings.ToLower(name)]; ok {
+		return secretType, nil
+	}
+	for _, secretType := range secretTypeNames {
+		if name == string(secretType) {
+			return secretType, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported secret type %q, must be one of: %s", name, strings.Join(SecretTypeNames(), ", "))
+}
+
+// ValidateSecretData checks that data has the keys the API server requires for
+// secretType, so invalid Secrets are refused before any request is made
+func ValidateSecretData(secretType v1.SecretType, data map[string][]byte) error {
+	var missing []string
+	require := func(keys ...string) {
+		for _, key := range keys {
+			if _, ok := data[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+	}
+
+	switch secretType {
+	case v1.SecretTypeTLS:
+		require(v1.TLSCertKey, v1.TLSPrivateKeyKey)
+	case v1.SecretTypeDockerConfigJson:
+		require(v1.DockerConfigJsonKey)
+		if raw, ok := data[v1.DockerConfigJsonKey]; ok && !json.Valid(raw) {
+			return fmt.Errorf("key %s of a %s secret must be valid JSON", v1.DockerConfigJsonKey, secretType)
+		}
+	case v1.SecretTypeDockercfg:
+		require(v1.DockerConfigKey)
+		if raw, ok := data[v1.DockerConfigKey]; ok && !json.Valid(raw) {
+			return fmt.Errorf("key %s of a %s secret must be valid JSON", v1.DockerConfigKey, secretType)
+		}
+	case v1.SecretTypeBasicAuth:
+		_, hasUsername := data[v1.BasicAuthUsernameKey]
+		_, hasPassword := data[v1.BasicAuthPasswordKey]
+		if !hasUsername && !hasPassword {
+			return fmt.Errorf("a %s secret requires at least one of the keys %s, %s", secretType, v1.BasicAuthUsernameKey, v1.BasicAuthPasswordKey)
+		}
+	case v1.SecretTypeSSHAuth:
+		require(v1.SSHAuthPrivateKey)
+	case v1.SecretTypeBootstrapToken:
+		require("token-id", "token-secret")
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("a %s secret requires the keys: %s", secretType, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// TLSData returns the data of a kubernetes.io/tls Secret
+func TLSData(cert, key []byte) map[string][]byte {
+	return map[string][]byte{
+		v1.TLSCertKey:       cert,
+		v1.TLSPrivateKeyKey: key,
+	}
+}
+
+// DockerConfigJSONData returns the data of a kubernetes.io/dockerconfigjson Secret
+func DockerConfigJSONData(dockerConfigJSON []byte) map[string][]byte {
+	return map[string][]byte{
+		v1.DockerConfigJsonKey: dockerConfigJSON,
+	}
+}
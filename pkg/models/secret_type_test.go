@@ -0,0 +1,59 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParseSecretType(t *testing.T) {
+	secretType, err := ParseSecretType("tls")
+	assert.NoError(t, err)
+	assert.Equal(t, v1.SecretTypeTLS, secretType)
+
+	secretType, err = ParseSecretType("kubernetes.io/basic-auth")
+	assert.NoError(t, err)
+	assert.Equal(t, v1.SecretTypeBasicAuth, secretType)
+
+	secretType, err = ParseSecretType("Opaque")
+	assert.NoError(t, err)
+	assert.Equal(t, v1.SecretTypeOpaque, secretType)
+
+	_, err = ParseSecretType("helm.sh/release.v1")
+	assert.Error(t, err)
+
+	_, err = ParseSecretType("service-account-token")
+	assert.Error(t, err, "Service account tokens cannot be written by ksec")
+}
+
+func TestValidateSecretData(t *testing.T) {
+	assert.NoError(t, ValidateSecretData(v1.SecretTypeOpaque, nil))
+	assert.NoError(t, ValidateSecretData(v1.SecretTypeTLS, TLSData([]byte("cert"), []byte("key"))))
+	assert.NoError(t, ValidateSecretData(v1.SecretTypeBasicAuth, map[string][]byte{"password": []byte("p")}))
+	assert.NoError(t, ValidateSecretData(v1.SecretTypeDockerConfigJson, DockerConfigJSONData([]byte(`{"auths":{}}`))))
+
+	assert.EqualError(t, ValidateSecretData(v1.SecretTypeTLS, map[string][]byte{"tls.crt": []byte("cert")}),
+		"a kubernetes.io/tls secret requires the keys: tls.key")
+	assert.EqualError(t, ValidateSecretData(v1.SecretTypeSSHAuth, nil),
+		"a kubernetes.io/ssh-auth secret requires the keys: ssh-privatekey")
+	assert.Error(t, ValidateSecretData(v1.SecretTypeBasicAuth, nil))
+	assert.Error(t, ValidateSecretData(v1.SecretTypeDockerConfigJson, DockerConfigJSONData([]byte("not json"))))
+}
+
+func TestCreateWithType(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	ctx := context.Background()
+
+	_, err := secretsClient.CreateWithType(ctx, expectedSecretName, v1.SecretTypeTLS, map[string][]byte{"tls.crt": []byte("cert")})
+	assert.Error(t, err, "Missing required keys should be refused")
+
+	secret, err := secretsClient.CreateWithType(ctx, expectedSecretName, v1.SecretTypeSSHAuth, map[string][]byte{v1.SSHAuthPrivateKey: []byte("key")})
+	assert.NoError(t, err)
+	assert.Equal(t, v1.SecretTypeSSHAuth, secret.Type)
+	assert.Contains(t, secret.Annotations, "ksec.io/ssh-privatekey")
+
+	_, err = secretsClient.Apply(ctx, secret, Mutation{Remove: []string{"ssh-privatekey"}})
+	assert.EqualError(t, err, "a kubernetes.io/ssh-auth secret requires the keys: ssh-privatekey")
+}
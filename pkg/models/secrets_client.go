@@ -1,33 +1,84 @@
 package models
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
+	"sort"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	apiv1 "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
 )
 
 // SecretsClient is a convenience wrapper for managing k8s Secrets
 type SecretsClient struct {
+	clientSet       kubernetes.Interface
 	secretInterface apiv1.SecretInterface
 	Namespace       string
 	AuthInfo        string
+
+	identity *identityResolver
+
+	// dryRun asks the API server to validate writes without persisting them
+	dryRun bool
+
+	// writeMode selects how changes to existing Secrets are sent
+	writeMode string
+}
+
+// ClientOptions select the cluster, namespace and identity used by a
+// SecretsClient. Empty fields fall back to the kubeconfig defaults.
+type ClientOptions struct {
+	Namespace  string
+	Context    string
+	Kubeconfig string
+
+	// Server, Token and CertificateAuthority connect without a kubeconfig
+	Server               string
+	Token                string
+	CertificateAuthority string
+
+	// Impersonate and ImpersonateGroups act as another user like kubectl --as and --as-group
+	Impersonate       string
+	ImpersonateGroups []string
+
+	// WriteMode is one of WriteModes, empty means WriteModeUpdate
+	WriteMode string
 }
 
 // NewSecretsClient constructor
-func NewSecretsClient(namespace string) (*SecretsClient, error) {
-	// initialize secrets client
-	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		clientcmd.NewDefaultClientConfigLoadingRules(),
-		&clientcmd.ConfigOverrides{},
-	)
-	config, err := kubeConfig.ClientConfig()
+func NewSecretsClient(opts *ClientOptions) (*SecretsClient, error) {
+	if err := validateWriteMode(opts.WriteMode); err != nil {
+		return nil, err
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = opts.Kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: opts.Context,
+		ClusterInfo: api.Cluster{
+			Server:               opts.Server,
+			CertificateAuthority: opts.CertificateAuthority,
+		},
+		AuthInfo: api.AuthInfo{
+			Token:             opts.Token,
+			Impersonate:       opts.Impersonate,
+			ImpersonateGroups: opts.ImpersonateGroups,
+		},
+	}
+
+	// initialize secrets client, client-go falls back to the service account
+	// of the pod when there is no kubeconfig
+	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+	config, err := clientConfig(kubeConfig, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -40,6 +91,7 @@ func NewSecretsClient(namespace string) (*SecretsClient, error) {
 		return nil, err
 	}
 
+	namespace := opts.Namespace
 	if namespace == "" {
 		namespace, _, err = kubeConfig.Namespace()
 		if err != nil {
@@ -47,13 +99,73 @@ func NewSecretsClient(namespace string) (*SecretsClient, error) {
 		}
 	}
 
+	kubeContext := opts.Context
+	if kubeContext == "" {
+		kubeContext = rawConfig.CurrentContext
+	}
+
+	authInfo := authInfoName(opts, rawConfig, kubeContext, config)
+
 	return &SecretsClient{
+		clientSet:       clientSet,
 		secretInterface: clientSet.CoreV1().Secrets(namespace),
 		Namespace:       namespace,
-		AuthInfo:        rawConfig.Contexts[rawConfig.CurrentContext].AuthInfo,
+		AuthInfo:        authInfo,
+		identity:        newIdentityResolver(clientSet, config, authInfo),
+		writeMode:       opts.WriteMode,
 	}, nil
 }
 
+// clientConfig returns the configuration of kubeConfig with the impersonation
+// options applied. The in-cluster configuration of client-go applies the
+// server, token and certificate authority overrides but not impersonation.
+func clientConfig(kubeConfig clientcmd.ClientConfig, opts *ClientOptions) (*rest.Config, error) {
+	config, err := kubeConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Impersonate != "" {
+		config.Impersonate.UserName = opts.Impersonate
+	}
+	if len(opts.ImpersonateGroups) > 0 {
+		config.Impersonate.Groups = opts.ImpersonateGroups
+	}
+	return config, nil
+}
+
+// Identity returns the user the API server authenticates the client as. It
+// is looked up on first use and falls back to AuthInfo.
+func (s *SecretsClient) Identity(ctx context.Context) Identity {
+	if s.identity == nil {
+		return Identity{Username: s.AuthInfo}
+	}
+	return s.identity.get(ctx)
+}
+
+// ForNamespace returns a copy of the client operating in another namespace
+func (s *SecretsClient) ForNamespace(namespace string) *SecretsClient {
+	client := *s
+	client.secretInterface = s.clientSet.CoreV1().Secrets(namespace)
+	client.Namespace = namespace
+	return &client
+}
+
+// WithDryRun returns a copy of the client whose writes are sent with the
+// server side dry run option, so admission webhooks validate them without
+// anything being persisted
+func (s *SecretsClient) WithDryRun() *SecretsClient {
+	client := *s
+	client.dryRun = true
+	return &client
+}
+
+func (s *SecretsClient) dryRunOption() []string {
+	if s.dryRun {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
 // List all Secrets
 func (s *SecretsClient) List(ctx context.Context) (*v1.SecretList, error) {
 	return s.secretInterface.List(ctx, metav1.ListOptions{})
@@ -61,43 +173,49 @@ func (s *SecretsClient) List(ctx context.Context) (*v1.SecretList, error) {
 
 // Create a new Secret
 func (s *SecretsClient) Create(ctx context.Context, name string) (*v1.Secret, error) {
-	secret := v1.Secret{
-		Type: v1.SecretTypeOpaque,
-		ObjectMeta: metav1.ObjectMeta{
-			Name: name,
-		},
-	}
-	return s.secretInterface.Create(ctx, &secret, metav1.CreateOptions{})
+	return s.CreateWithType(ctx, name, v1.SecretTypeOpaque, nil)
 }
 
 // CreateWithData creates a new Secret and passed in Data keys
 func (s *SecretsClient) CreateWithData(ctx context.Context, name string, data map[string][]byte) (*v1.Secret, error) {
+	return s.CreateWithType(ctx, name, v1.SecretTypeOpaque, data)
+}
+
+// CreateWithType creates a new Secret of secretType, after checking data has
+// the keys the type requires. In WriteModeApply it is created with
+// server-side apply.
+func (s *SecretsClient) CreateWithType(ctx context.Context, name string, secretType v1.SecretType, data map[string][]byte) (*v1.Secret, error) {
+	if err := ValidateSecretData(secretType, data); err != nil {
+		return nil, err
+	}
 
-	annotation := NewKeyAnnotation(s.AuthInfo)
 	annotations := make(map[string]string)
 
-	for key := range data {
-		jsonBytes, err := json.Marshal(annotation)
+	for key, value := range data {
+		annotation, err := s.keyAnnotationJSON(ctx, key, value)
 		if err != nil {
 			return nil, err
 		}
-		annotations[fmt.Sprintf("%s/%s", annotationPrefix, key)] = string(jsonBytes)
+		annotations[fmt.Sprintf("%s/%s", annotationPrefix, key)] = annotation
 	}
 
 	secret := v1.Secret{
-		Type: v1.SecretTypeOpaque,
+		Type: secretType,
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        name,
 			Annotations: annotations,
 		},
 		Data: data,
 	}
-	return s.secretInterface.Create(ctx, &secret, metav1.CreateOptions{})
+	if s.writeMode == WriteModeApply {
+		return s.createApplied(ctx, &secret)
+	}
+	return s.secretInterface.Create(ctx, &secret, metav1.CreateOptions{DryRun: s.dryRunOption(), FieldManager: FieldManager})
 }
 
 // Delete a secret
 func (s *SecretsClient) Delete(ctx context.Context, name string) error {
-	return s.secretInterface.Delete(ctx, name, metav1.DeleteOptions{})
+	return s.secretInterface.Delete(ctx, name, metav1.DeleteOptions{DryRun: s.dryRunOption()})
 }
 
 // Get Secret
@@ -120,7 +238,8 @@ func (s *SecretsClient) GetKey(ctx context.Context, name, key string) (string, e
 	return string(value), nil
 }
 
-// Update Secret keys
+// Update Secret keys. Only keys whose value differs from the one stored in
+// secret have their annotation updated.
 func (s *SecretsClient) Update(ctx context.Context, secret *v1.Secret, data map[string][]byte) (*v1.Secret, error) {
 	if secret.Data == nil {
 		secret.Data = make(map[string][]byte)
@@ -129,24 +248,51 @@ func (s *SecretsClient) Update(ctx context.Context, secret *v1.Secret, data map[
 		secret.Annotations = make(map[string]string)
 	}
 
-	annotation := NewKeyAnnotation(s.AuthInfo)
 	for key, value := range data {
+		if current, ok := secret.Data[key]; ok && bytes.Equal(current, value) {
+			continue
+		}
 		secret.Data[key] = value
-		jsonBytes, err := json.Marshal(annotation)
+		annotation, err := s.keyAnnotationJSON(ctx, key, value)
 		if err != nil {
 			return nil, err
 		}
-		secret.Annotations[fmt.Sprintf("%s/%s", annotationPrefix, key)] = string(jsonBytes)
+		secret.Annotations[fmt.Sprintf("%s/%s", annotationPrefix, key)] = annotation
 	}
 
-	return s.secretInterface.Update(ctx, secret, metav1.UpdateOptions{})
+	return s.secretInterface.Update(ctx, secret, metav1.UpdateOptions{DryRun: s.dryRunOption(), FieldManager: FieldManager})
+}
+
+// StaleKeys returns the sorted Secret keys that are not present in data
+func StaleKeys(secret *v1.Secret, data map[string][]byte) []string {
+	var keys []string
+	for key := range secret.Data {
+		if _, ok := data[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
 }
 
-// Upsert creates a Secret if needed and updates Secret keys
+// RemoveKeys deletes keys and their annotations from a Secret, the change is
+// persisted by the next Update
+func RemoveKeys(secret *v1.Secret, keys []string) {
+	for _, key := range keys {
+		delete(secret.Data, key)
+		delete(secret.Annotations, fmt.Sprintf("%s/%s", annotationPrefix, key))
+	}
+}
+
+// Upsert creates a Secret if it does not exist and updates Secret keys
+// otherwise. Errors other than NotFound from reading the Secret are returned.
 func (s *SecretsClient) Upsert(ctx context.Context, name string, data map[string][]byte) (*v1.Secret, error) {
 	secret, err := s.Get(ctx, name)
-	if err != nil {
+	if apierrors.IsNotFound(err) {
 		return s.CreateWithData(ctx, name, data)
 	}
-	return s.Update(ctx, secret, data)
+	if err != nil {
+		return nil, err
+	}
+	return s.Apply(ctx, secret, Mutation{Set: data})
 }
@@ -2,10 +2,20 @@ package models
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
 )
 
 var secretsClient *SecretsClient
@@ -134,6 +144,35 @@ func TestUpdate(t *testing.T) {
 	assert.Equal(t, "newvalue", string(secret.Data["key"]), "Key value should be updated to 'newvalue'")
 }
 
+func TestUpdateOnlyAnnotatesChangedKeys(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	ctx := context.Background()
+
+	secret, err := secretsClient.CreateWithData(ctx, expectedSecretName, map[string][]byte{
+		"same":    []byte("value"),
+		"changed": []byte("old"),
+	})
+	assert.NoError(t, err)
+	sameAnnotation := secret.Annotations["ksec.io/same"]
+	changedAnnotation := secret.Annotations["ksec.io/changed"]
+
+	secret, err = secretsClient.Update(ctx, secret, map[string][]byte{
+		"same":    []byte("value"),
+		"changed": []byte("new"),
+		"added":   []byte("value"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, sameAnnotation, secret.Annotations["ksec.io/same"], "unchanged keys keep their metadata")
+	assert.NotEqual(t, changedAnnotation, secret.Annotations["ksec.io/changed"])
+	assert.Contains(t, secret.Annotations, "ksec.io/added")
+
+	RemoveKeys(secret, []string{"changed"})
+	secret, err = secretsClient.Update(ctx, secret, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, sameAnnotation, secret.Annotations["ksec.io/same"], "removing a key leaves the others untouched")
+	assert.NotContains(t, secret.Data, "changed")
+}
+
 func TestUpsert(t *testing.T) {
 	setupTestClient(defaultNamespace)
 	ctx := context.Background()
@@ -153,3 +192,182 @@ func TestUpsert(t *testing.T) {
 	assert.NoError(t, err, "Upserting (updating) secret should not return an error")
 	assert.Equal(t, "upserted", string(secret.Data["key"]), "Key value should be 'upserted'")
 }
+
+func TestUpsertOnlyCreatesOnNotFound(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	ctx := context.Background()
+
+	clientSet := secretsClient.clientSet.(*testclient.Clientset)
+	clientSet.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(action.GetResource().GroupResource(), expectedSecretName, errors.New("denied"))
+	})
+
+	_, err := secretsClient.Upsert(ctx, expectedSecretName, map[string][]byte{"key": []byte("value")})
+	assert.True(t, apierrors.IsForbidden(err), "Errors other than NotFound should be returned")
+	for _, action := range clientSet.Actions() {
+		assert.NotEqual(t, "create", action.GetVerb(), "The Secret should not be created")
+	}
+}
+
+func TestRemoveStaleKeys(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	ctx := context.Background()
+
+	secret, err := secretsClient.CreateWithData(ctx, expectedSecretName, map[string][]byte{
+		"keep":   []byte("value"),
+		"stale1": []byte("value"),
+		"stale2": []byte("value"),
+	})
+	assert.NoError(t, err, "Creating secret with data should not return an error")
+
+	data := map[string][]byte{"keep": []byte("value"), "new": []byte("value")}
+	stale := StaleKeys(secret, data)
+	assert.Equal(t, []string{"stale1", "stale2"}, stale)
+
+	RemoveKeys(secret, stale)
+	secret, err = secretsClient.Update(ctx, secret, data)
+	assert.NoError(t, err, "Updating secret should not return an error")
+
+	assert.Equal(t, data, secret.Data)
+	assert.NotContains(t, secret.Annotations, "ksec.io/stale1")
+	assert.Contains(t, secret.Annotations, "ksec.io/new")
+}
+
+func TestWithDryRun(t *testing.T) {
+	setupTestClient(defaultNamespace)
+
+	dryRunClient := secretsClient.WithDryRun()
+	assert.Equal(t, []string{"All"}, dryRunClient.dryRunOption())
+	assert.Nil(t, secretsClient.dryRunOption(), "The original client should not be modified")
+}
+
+func TestForNamespace(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	ctx := context.Background()
+
+	other := secretsClient.ForNamespace("other")
+	assert.Equal(t, "other", other.Namespace)
+	assert.Equal(t, defaultNamespace, secretsClient.Namespace, "The original client should not be modified")
+
+	_, err := other.Create(ctx, expectedSecretName)
+	assert.NoError(t, err, "Creating secret in another namespace should not return an error")
+
+	_, err = secretsClient.Get(ctx, expectedSecretName)
+	assert.Error(t, err, "Secret should not exist in the default namespace")
+
+	secret, err := other.Get(ctx, expectedSecretName)
+	assert.NoError(t, err, "Getting secret from another namespace should not return an error")
+	assert.Equal(t, "other", secret.Namespace)
+}
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com
+- name: prod-cluster
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+    namespace: dev-namespace
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: prod-user
+    namespace: prod-namespace
+users:
+- name: dev-user
+  user:
+    token: dev-token
+- name: prod-user
+  user:
+    token: prod-token
+`
+
+func writeTestKubeconfig(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "config")
+	assert.NoError(t, os.WriteFile(path, []byte(testKubeconfig), 0600))
+	return path
+}
+
+func TestNewSecretsClientOptions(t *testing.T) {
+	kubeconfig := writeTestKubeconfig(t)
+
+	client, err := NewSecretsClient(&ClientOptions{Kubeconfig: kubeconfig})
+	assert.NoError(t, err)
+	assert.Equal(t, "dev-namespace", client.Namespace)
+	assert.Equal(t, "dev-user", client.AuthInfo)
+
+	client, err = NewSecretsClient(&ClientOptions{Kubeconfig: kubeconfig, Context: "prod", Namespace: "other"})
+	assert.NoError(t, err)
+	assert.Equal(t, "other", client.Namespace)
+	assert.Equal(t, "prod-user", client.AuthInfo)
+
+	client, err = NewSecretsClient(&ClientOptions{Kubeconfig: kubeconfig, Impersonate: "jane", ImpersonateGroups: []string{"admins"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "jane", client.AuthInfo)
+
+	_, err = NewSecretsClient(&ClientOptions{Kubeconfig: kubeconfig, Context: "doesnotexist"})
+	assert.Error(t, err)
+}
+
+func TestNewSecretsClientWithoutKubeconfig(t *testing.T) {
+	emptyKubeconfig := filepath.Join(t.TempDir(), "config")
+	assert.NoError(t, os.WriteFile(emptyKubeconfig, []byte{}, 0600))
+
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	_, err = NewSecretsClient(&ClientOptions{Kubeconfig: emptyKubeconfig})
+	assert.True(t, clientcmd.IsEmptyConfig(err), "Creating a client without kubeconfig outside of a cluster should return the missing configuration error, got: %v", err)
+
+	_, err = NewSecretsClient(&ClientOptions{Kubeconfig: emptyKubeconfig, Token: "token"})
+	assert.True(t, clientcmd.IsEmptyConfig(err), "A token without a server should not be ignored, got: %v", err)
+
+	client, err := NewSecretsClient(&ClientOptions{
+		Kubeconfig: emptyKubeconfig,
+		Server:     "https://ci.example.com",
+		Token:      "header.eyJzdWIiOiJzeXN0ZW06c2VydmljZWFjY291bnQ6Y2k6ZGVwbG95ZXIifQ.signature",
+		Namespace:  "ci",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "ci", client.Namespace)
+	assert.Equal(t, "system:serviceaccount:ci:deployer", client.AuthInfo)
+}
+
+// kubeconfig has the methods of clientcmd.ClientConfig that tests do not use
+type kubeconfig interface {
+	RawConfig() (api.Config, error)
+	Namespace() (string, bool, error)
+	ConfigAccess() clientcmd.ConfigAccess
+}
+
+// inClusterClientConfig returns a configuration like the one client-go reads
+// from the service account of a pod, with no impersonation
+type inClusterClientConfig struct {
+	kubeconfig
+}
+
+func (inClusterClientConfig) ClientConfig() (*rest.Config, error) {
+	return &rest.Config{
+		Host:            "https://10.0.0.1:443",
+		BearerTokenFile: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+	}, nil
+}
+
+func TestClientConfigInCluster(t *testing.T) {
+	t.Parallel()
+
+	config, err := clientConfig(inClusterClientConfig{}, &ClientOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, config.Impersonate.UserName)
+
+	config, err = clientConfig(inClusterClientConfig{}, &ClientOptions{Impersonate: "jane", ImpersonateGroups: []string{"admins"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://10.0.0.1:443", config.Host)
+	assert.Equal(t, rest.ImpersonationConfig{UserName: "jane", Groups: []string{"admins"}}, config.Impersonate, "Impersonation should be applied to the in-cluster configuration")
+}
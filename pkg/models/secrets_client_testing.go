@@ -2,6 +2,7 @@ package models
 
 import (
 	testclient "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/clientcmd/api"
 )
@@ -14,10 +15,13 @@ func MockNewSecretsClient(config clientcmd.ClientConfig, namespace string) (*Sec
 		}
 	}
 
+	clientSet := testclient.NewSimpleClientset()
 	return &SecretsClient{
-		secretInterface: testclient.NewSimpleClientset().CoreV1().Secrets(namespace),
+		clientSet:       clientSet,
+		secretInterface: clientSet.CoreV1().Secrets(namespace),
 		Namespace:       namespace,
 		AuthInfo:        "testuser",
+		identity:        newIdentityResolver(clientSet, &rest.Config{}, "testuser"),
 	}, nil
 }
 
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/client-go/util/csaupgrade"
+)
+
+// FieldManager is the name ksec writes under, the API server uses it to
+// track which fields of a Secret ksec owns
+const FieldManager = "ksec"
+
+// Write modes select how changes to existing Secrets are sent
+const (
+	// WriteModeUpdate replaces the whole Secret, guarded by its resourceVersion
+	WriteModeUpdate = "update"
+	// WriteModePatch sends a strategic merge patch of the changed keys only
+	WriteModePatch = "patch"
+	// WriteModeApply uses server-side apply, so conflicts with other field
+	// managers are reported by the API server
+	WriteModeApply = "apply"
+)
+
+// WriteModes lists the supported write modes
+var WriteModes = []string{WriteModeUpdate, WriteModePatch, WriteModeApply}
+
+func validateWriteMode(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	for _, supported := range WriteModes {
+		if mode == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported write mode %q, must be one of: %s", mode, strings.Join(WriteModes, ", "))
+}
+
+// Patch makes the changes of m with a strategic merge patch. Only keys whose
+// value differs from base and removed keys are sent, so fields other clients
+// set on the Secret are left alone.
+func (s *SecretsClient) Patch(ctx context.Context, base *v1.Secret, m Mutation) (*v1.Secret, error) {
+	data := make(map[string]interface{})
+	annotations := make(map[string]interface{})
+
+	for key, value := range m.Set {
+		if current, ok := base.Data[key]; ok && bytes.Equal(current, value) {
+			continue
+		}
+		annotation, err := s.keyAnnotationJSON(ctx, key, value)
+		if err != nil {
+			return nil, err
+		}
+		data[key] = value
+		annotations[fmt.Sprintf("%s/%s", annotationPrefix, key)] = annotation
+	}
+
+	// null removes a key from a map in merge patches
+	for _, key := range m.Remove {
+		data[key] = nil
+		annotations[fmt.Sprintf("%s/%s", annotationPrefix, key)] = nil
+	}
+
+	if len(data) == 0 {
+		return base, nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+		"data":     data,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.secretInterface.Patch(ctx, base.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{
+		DryRun:       s.dryRunOption(),
+		FieldManager: FieldManager,
+	})
+}
+
+// ServerSideApply makes the changes of m with server-side apply. The fields
+// ksec already owns are read from the managed fields of base, so keys it set
+// before are kept, including those written in another write mode. Keys owned by other field managers cannot be removed this
+// way, and changing them fails with a conflict naming the other manager.
+func (s *SecretsClient) ServerSideApply(ctx context.Context, base *v1.Secret, m Mutation) (*v1.Secret, error) {
+	base, force, err := s.takeOverFields(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	config, err := corev1ac.ExtractSecret(base, FieldManager)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range m.Remove {
+		if _, ok := config.Data[key]; !ok {
+			if _, exists := base.Data[key]; exists {
+				return nil, fmt.Errorf("key %s of secret %s is not managed by %s and cannot be removed with server-side apply", key, base.Name, FieldManager)
+			}
+		}
+		delete(config.Data, key)
+		delete(config.Annotations, fmt.Sprintf("%s/%s", annotationPrefix, key))
+	}
+
+	for key, value := range m.Set {
+		annotationKey := fmt.Sprintf("%s/%s", annotationPrefix, key)
+		annotation, ok := base.Annotations[annotationKey]
+
+		if current, exists := base.Data[key]; !exists || !bytes.Equal(current, value) || !ok {
+			annotation, err = s.keyAnnotationJSON(ctx, key, value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		config.WithData(map[string][]byte{key: value})
+		config.WithAnnotations(map[string]string{annotationKey: annotation})
+	}
+
+	return s.secretInterface.Apply(ctx, config, metav1.ApplyOptions{
+		DryRun:       s.dryRunOption(),
+		FieldManager: FieldManager,
+		Force:        force,
+	})
+}
+
+// takeOverFields moves the fields owned by the Update entry of ksec, left by
+// the other write modes, to its Apply entry. The API server treats both as
+// different managers, so ksec would otherwise conflict with itself and not
+// find the keys it set. A dry run cannot persist the move, it takes over the
+// fields of a copy of base and returns that the apply must be forced.
+func (s *SecretsClient) takeOverFields(ctx context.Context, base *v1.Secret) (*v1.Secret, bool, error) {
+	managers := sets.New(FieldManager)
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(base, managers, FieldManager)
+	if err != nil || patch == nil {
+		return base, false, err
+	}
+
+	if s.dryRun {
+		upgraded := base.DeepCopy()
+		if err := csaupgrade.UpgradeManagedFields(upgraded, managers, FieldManager); err != nil {
+			return nil, false, err
+		}
+		return upgraded, true, nil
+	}
+
+	// the patch also sets the resourceVersion of base, so it fails with a
+	// conflict when the Secret changed since it was read
+	upgraded, err := s.secretInterface.Patch(ctx, base.Name, types.JSONPatchType, patch, metav1.PatchOptions{})
+	return upgraded, false, err
+}
+
+// createApplied creates a Secret with server-side apply, so its fields are
+// owned by the Apply entry of ksec that ServerSideApply reads
+func (s *SecretsClient) createApplied(ctx context.Context, secret *v1.Secret) (*v1.Secret, error) {
+	// apply also updates existing Secrets, creating one must still fail then
+	if _, err := s.Get(ctx, secret.Name); err == nil {
+		return nil, apierrors.NewAlreadyExists(v1.Resource("secrets"), secret.Name)
+	} else if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	config := corev1ac.Secret(secret.Name, s.Namespace).
+		WithType(secret.Type).
+		WithAnnotations(secret.Annotations).
+		WithData(secret.Data)
+	return s.secretInterface.Apply(ctx, config, metav1.ApplyOptions{
+		DryRun:       s.dryRunOption(),
+		FieldManager: FieldManager,
+	})
+}
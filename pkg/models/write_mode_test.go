@@ -0,0 +1,149 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	testclient "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestPatchKeepsOtherFields(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	secretsClient.writeMode = WriteModePatch
+	ctx := context.Background()
+
+	base, err := secretsClient.CreateWithData(ctx, expectedSecretName, map[string][]byte{
+		"same": []byte("value"),
+		"key":  []byte("old"),
+		"gone": []byte("old"),
+	})
+	assert.NoError(t, err)
+	sameAnnotation := base.Annotations["ksec.io/same"]
+
+	// another controller labels the Secret after ksec read it
+	labeled := base.DeepCopy()
+	labeled.Labels = map[string]string{"reflector": "true"}
+	_, err = secretsClient.secretInterface.Update(ctx, labeled, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	secret, err := secretsClient.Apply(ctx, base, Mutation{
+		Set:    map[string][]byte{"same": []byte("value"), "key": []byte("new")},
+		Remove: []string{"gone"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"reflector": "true"}, secret.Labels, "Fields set by others should be kept")
+	assert.Equal(t, map[string][]byte{"same": []byte("value"), "key": []byte("new")}, secret.Data)
+	assert.Equal(t, sameAnnotation, secret.Annotations["ksec.io/same"])
+	assert.NotContains(t, secret.Annotations, "ksec.io/gone")
+
+	actions := secretsClient.clientSet.(*testclient.Clientset).Actions()
+	patch := actions[len(actions)-1].(k8stesting.PatchAction)
+	assert.Equal(t, types.StrategicMergePatchType, patch.GetPatchType())
+
+	sent := map[string]map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(patch.GetPatch(), &sent))
+	assert.NotContains(t, sent["data"], "same", "Unchanged keys should not be sent")
+	assert.Contains(t, sent["data"], "gone")
+	assert.Nil(t, sent["data"]["gone"])
+}
+
+func TestServerSideApply(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	secretsClient.writeMode = WriteModeApply
+	applyCreates(secretsClient.clientSet.(*testclient.Clientset))
+	ctx := context.Background()
+
+	base, err := secretsClient.CreateWithData(ctx, expectedSecretName, map[string][]byte{"key": []byte("old")})
+	assert.NoError(t, err)
+	assert.Equal(t, "old", string(base.Data["key"]))
+
+	actions := secretsClient.clientSet.(*testclient.Clientset).Actions()
+	patch := actions[len(actions)-1].(k8stesting.PatchAction)
+	assert.Equal(t, types.ApplyPatchType, patch.GetPatchType(), "New Secrets should be created with server-side apply")
+
+	_, err = secretsClient.CreateWithData(ctx, expectedSecretName, nil)
+	assert.True(t, apierrors.IsAlreadyExists(err), "Creating an existing Secret should fail")
+
+	secret, err := secretsClient.Apply(ctx, base, Mutation{Set: map[string][]byte{"key": []byte("new")}})
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(secret.Data["key"]))
+
+	actions = secretsClient.clientSet.(*testclient.Clientset).Actions()
+	patch = actions[len(actions)-1].(k8stesting.PatchAction)
+	assert.Equal(t, types.ApplyPatchType, patch.GetPatchType())
+	assert.Contains(t, string(patch.GetPatch()), `"ksec.io/key"`)
+
+	// the fake client does not track managed fields, so ksec owns nothing
+	_, err = secretsClient.Apply(ctx, secret, Mutation{Remove: []string{"key"}})
+	assert.EqualError(t, err, "key key of secret test-secret is not managed by ksec and cannot be removed with server-side apply")
+}
+
+func TestServerSideApplyTakesOverUpdatedFields(t *testing.T) {
+	setupTestClient(defaultNamespace)
+	ctx := context.Background()
+
+	// written by ksec in update mode
+	base, err := secretsClient.CreateWithData(ctx, expectedSecretName, map[string][]byte{"key": []byte("old"), "other": []byte("value")})
+	assert.NoError(t, err)
+	base.ManagedFields = []metav1.ManagedFieldsEntry{{
+		Manager:    FieldManager,
+		Operation:  metav1.ManagedFieldsOperationUpdate,
+		APIVersion: "v1",
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:data":{".":{},"f:key":{},"f:other":{}},"f:metadata":{"f:annotations":{".":{},"f:ksec.io/key":{},"f:ksec.io/other":{}}}}`)},
+	}}
+	base, err = secretsClient.secretInterface.Update(ctx, base, metav1.UpdateOptions{})
+	assert.NoError(t, err)
+
+	secretsClient.writeMode = WriteModeApply
+	_, err = secretsClient.Apply(ctx, base, Mutation{Remove: []string{"key"}})
+	assert.NoError(t, err, "Keys ksec wrote in update mode should be removable")
+
+	actions := secretsClient.clientSet.(*testclient.Clientset).Actions()
+	upgrade := actions[len(actions)-2].(k8stesting.PatchAction)
+	assert.Equal(t, types.JSONPatchType, upgrade.GetPatchType(), "The fields should be taken over before applying")
+	apply := actions[len(actions)-1].(k8stesting.PatchAction)
+	assert.Equal(t, types.ApplyPatchType, apply.GetPatchType())
+	assert.NotContains(t, string(apply.GetPatch()), `"key"`)
+	assert.Contains(t, string(apply.GetPatch()), `"other"`, "The other keys ksec wrote should be kept")
+
+	secret, err := secretsClient.Get(ctx, expectedSecretName)
+	assert.NoError(t, err)
+	assert.Len(t, secret.ManagedFields, 1)
+	assert.Equal(t, metav1.ManagedFieldsOperationApply, secret.ManagedFields[0].Operation)
+	assert.Equal(t, FieldManager, secret.ManagedFields[0].Manager)
+}
+
+// applyCreates makes the fake client create Secrets that are applied before
+// they exist, as the API server does
+func applyCreates(client *testclient.Clientset) {
+	client.PrependReactor("patch", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		if _, err := client.Tracker().Get(action.GetResource(), action.GetNamespace(), patch.GetName()); !apierrors.IsNotFound(err) {
+			return false, nil, nil
+		}
+
+		secret := &v1.Secret{}
+		if err := json.Unmarshal(patch.GetPatch(), secret); err != nil {
+			return true, nil, err
+		}
+		return true, secret, client.Tracker().Create(action.GetResource(), secret, action.GetNamespace())
+	})
+}
+
+func TestValidateWriteMode(t *testing.T) {
+	assert.NoError(t, validateWriteMode(""))
+	assert.NoError(t, validateWriteMode(WriteModeApply))
+	assert.EqualError(t, validateWriteMode("replace"), `unsupported write mode "replace", must be one of: update, patch, apply`)
+}